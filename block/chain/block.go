@@ -52,6 +52,13 @@ func NewBlock(prevHash []byte, prevDiffculty uint64) *Block {
 	}
 }
 
+// Header 返回不含交易列表的区块头
+func (block *Block) Header() *Block {
+	header := *block
+	header.Transactions = nil
+	return &header
+}
+
 func (block *Block) Save() {
 	// 省略区块保存实现
 	// 2. 序列化
@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 
 	"github.com/Alan-333333/simple-blockchain/transaction"
+	"github.com/Alan-333333/simple-blockchain/utils"
 	"github.com/Alan-333333/simple-blockchain/wallet"
 )
 
@@ -88,7 +89,110 @@ func (bc *Blockchain) GetLastBlock() *Block {
 // 判断区块是否valid
 // IsValidBlock 验证区块是否合法
 func (bc *Blockchain) IsValidBlock(block *Block) bool {
-	return bc.consensus.VerifyBlock(block)
+	if !bc.consensus.VerifyBlock(block) {
+		return false
+	}
+
+	// 验证交易序号与链上状态连续
+	return bc.verifyNonces(block)
+}
+
+// GetHeight 返回当前区块高度
+func (bc *Blockchain) GetHeight() int {
+	return len(bc.blocks)
+}
+
+// IsValidTransaction 交易池使用的交易规则校验
+func (bc *Blockchain) IsValidTransaction(tx *transaction.Transaction) bool {
+	return IsValidTransaction(tx)
+}
+
+// GetNonce 返回地址下一笔交易应使用的序号
+func (bc *Blockchain) GetNonce(address string) uint64 {
+
+	var nonce uint64
+	for _, block := range bc.blocks {
+		for _, tx := range block.Transactions {
+			if tx.Sender == address {
+				nonce = tx.Nonce + 1
+			}
+		}
+	}
+
+	return nonce
+}
+
+// IsAddressUsed 地址是否出现在链上的交易中
+func (bc *Blockchain) IsAddressUsed(address string) bool {
+
+	for _, block := range bc.blocks {
+		for _, tx := range block.Transactions {
+			if tx.Sender == address || tx.Recipient == address {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// AddressTx 地址相关的链上交易
+type AddressTx struct {
+	Tx        *transaction.Transaction
+	Height    int
+	BlockHash []byte
+}
+
+// GetAddressHistory 按区块顺序返回地址作为发送方或接收方的所有交易
+func (bc *Blockchain) GetAddressHistory(address string) []AddressTx {
+
+	var history []AddressTx
+	for height, block := range bc.blocks {
+		for _, tx := range block.Transactions {
+			if tx.Sender == address || tx.Recipient == address {
+				history = append(history, AddressTx{Tx: tx, Height: height, BlockHash: block.Hash})
+			}
+		}
+	}
+
+	return history
+}
+
+// GetBalance 根据链上交易计算地址余额: 收到的金额减去发出的金额和手续费
+func (bc *Blockchain) GetBalance(address string) transaction.Amount {
+
+	var balance transaction.Amount
+	for _, entry := range bc.GetAddressHistory(address) {
+		if entry.Tx.Recipient == address {
+			balance += entry.Tx.Value
+		}
+		if entry.Tx.Sender == address {
+			balance -= entry.Tx.Value + entry.Tx.Fee
+		}
+	}
+
+	return balance
+}
+
+// 验证区块中每个发送方的交易序号从链上序号开始连续递增
+func (bc *Blockchain) verifyNonces(block *Block) bool {
+
+	expected := make(map[string]uint64)
+	for _, tx := range block.Transactions {
+		nonce, ok := expected[tx.Sender]
+		if !ok {
+			nonce = bc.GetNonce(tx.Sender)
+		}
+
+		// 旧的或重复的序号
+		if tx.Nonce != nonce {
+			fmt.Println("err Nonce")
+			return false
+		}
+		expected[tx.Sender] = nonce + 1
+	}
+
+	return true
 }
 
 // IsValidTransaction 交易合法性校验
@@ -99,7 +203,18 @@ func IsValidTransaction(tx *transaction.Transaction) bool {
 		return false
 	}
 
-	if tx.Value <= 0 {
+	// 接收方必须是规范格式的合法地址
+	if recipient, err := utils.NormalizeAddress(tx.Recipient); err != nil || recipient != tx.Recipient {
+		return false
+	}
+
+	// 金额必须为正且不超过上限
+	if tx.Value <= 0 || !tx.Value.IsValid() {
+		return false
+	}
+
+	// 手续费不能为负,金额加手续费不能超过上限
+	if _, err := tx.Value.Add(tx.Fee); err != nil {
 		return false
 	}
 
@@ -123,20 +238,21 @@ func (bc *Blockchain) Mine(pool *transaction.TxPool) {
 		if block == nil {
 			continue
 		}
+		// 填充交易和Merkle根
+		block.Transactions = txs
+		block.MerkleRoot = CalcMerkleRoot(txs)
+
 		// 3. 挖矿
 		// doPoW(block)
 		bc.consensus.GenerateBlock(block)
 
-		// 填充交易
-		block.Transactions = txs
-
 		// 4. 添加区块
 		err := bc.AddBlock(block)
 		if err != nil {
 			fmt.Println(err)
 		}
 
-		pool.RemoveTransactions(txs)
+		pool.RemoveConfirmed(txs)
 
 		bc.Save()
 	}
@@ -211,27 +327,40 @@ func (bc *Blockchain) Save() error {
 	return err
 }
 
-// Load 加载区块链
-func LoadBlockchain() (*Blockchain, error) {
+// LoadBlockchain 加载保存的区块链,没有保存的区块链时创建空的区块链
+// 加载的区块链作为全局实例,启动时应在使用区块链之前调用
+func LoadBlockchain(consensus Consensus) (*Blockchain, error) {
 
-	// 1. 读取序列化数据
+	// 1. 读取序列化数据,文件不存在时为空的区块链
 	raw, err := os.ReadFile(chainFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewBlockchain(consensus), nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	// 2. 反序列化
-	bc, _ := deserialize(raw)
-
-	meta := bc.GetMetadata()
-	// 3. 校验元数据的完整性
-	err = bc.VerifyMetadata(meta)
 
+	// 2. 反序列化
+	bc, err := deserialize(raw)
 	if err != nil {
 		return nil, err
 	}
 
-	return bc, nil
+	// 3. 校验元数据的完整性
+	if len(bc.blocks) > 0 {
+		meta, err := LoadMetadata()
+		if err != nil {
+			return nil, err
+		}
+		if err := bc.VerifyMetadata(meta); err != nil {
+			return nil, err
+		}
+	}
 
+	bc.consensus = consensus
+	blockchainInstance = bc
+
+	return bc, nil
 }
 
 // GetMetadata 获取元数据
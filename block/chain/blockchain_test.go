@@ -0,0 +1,40 @@
+package blockchain
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadBlockchain(t *testing.T) {
+
+	dir, _ := os.Getwd()
+	os.Chdir(t.TempDir())
+	defer os.Chdir(dir)
+	defer func() { blockchainInstance = nil }()
+
+	// 1. 没有保存的区块链时为空
+	blockchainInstance = nil
+	bc, err := LoadBlockchain(&POW{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, bc.GetHeight())
+
+	// 2. 保存后重新加载
+	genesis := CreateGenesisBlock()
+	assert.NoError(t, bc.AddBlock(genesis))
+	assert.NoError(t, bc.Save())
+
+	blockchainInstance = nil
+	loaded, err := LoadBlockchain(&POW{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, loaded.GetHeight())
+	assert.Equal(t, genesis.Hash, loaded.GetBlocks()[0].Hash)
+	assert.Same(t, loaded, GetBlockchain())
+
+	// 3. 元数据不一致时加载失败,不创建新的区块链
+	meta := &BlockchainMetadata{LastBlockHash: []byte("other"), BlockCount: 1}
+	assert.NoError(t, meta.Save())
+	_, err = LoadBlockchain(&POW{})
+	assert.Error(t, err)
+}
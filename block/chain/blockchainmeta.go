@@ -29,3 +29,19 @@ func (m *BlockchainMetadata) Save() error {
 	// 2. 写入文件
 	return os.WriteFile(chainMetaFile, metaJson, 0600)
 }
+
+// LoadMetadata 读取保存的区块链元数据
+func LoadMetadata() (*BlockchainMetadata, error) {
+
+	raw, err := os.ReadFile(chainMetaFile)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &BlockchainMetadata{}
+	if err := json.Unmarshal(raw, meta); err != nil {
+		return nil, err
+	}
+
+	return meta, nil
+}
@@ -0,0 +1,205 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+
+	"github.com/Alan-333333/simple-blockchain/transaction"
+)
+
+// PartialMerkleTree 部分Merkle树,只包含证明匹配交易所需的hash
+type PartialMerkleTree struct {
+	// 区块中的交易总数
+	Total uint32
+
+	// 深度优先遍历时用到的hash
+	Hashes [][]byte
+
+	// 深度优先遍历时每个节点的标志位,按字节打包
+	Flags []byte
+}
+
+// 计算两个节点的父hash
+func hashMerkleBranches(left []byte, right []byte) []byte {
+	data := append(append([]byte{}, left...), right...)
+	first := sha256.Sum256(data)
+	second := sha256.Sum256(first[:])
+	return second[:]
+}
+
+// CalcMerkleRoot 计算交易列表的Merkle根
+func CalcMerkleRoot(txs []*transaction.Transaction) []byte {
+
+	if len(txs) == 0 {
+		return nil
+	}
+
+	// 1. 叶子节点为交易ID
+	level := make([][]byte, len(txs))
+	for i, tx := range txs {
+		level[i] = tx.ID()
+	}
+
+	// 2. 逐层向上计算,奇数个节点时复制最后一个
+	for len(level) > 1 {
+		if len(level)%2 != 0 {
+			level = append(level, level[len(level)-1])
+		}
+		next := make([][]byte, 0, len(level)/2)
+		for i := 0; i < len(level); i += 2 {
+			next = append(next, hashMerkleBranches(level[i], level[i+1]))
+		}
+		level = next
+	}
+
+	return level[0]
+}
+
+// NewPartialMerkleTree 根据交易ID和匹配标记构造部分Merkle树
+func NewPartialMerkleTree(txids [][]byte, matches []bool) *PartialMerkleTree {
+
+	tree := &PartialMerkleTree{Total: uint32(len(txids))}
+	if len(txids) == 0 {
+		return tree
+	}
+
+	var bits []bool
+	tree.traverseAndBuild(tree.height(), 0, txids, matches, &bits)
+
+	// 标志位按字节打包,低位在前
+	tree.Flags = make([]byte, (len(bits)+7)/8)
+	for i, bit := range bits {
+		if bit {
+			tree.Flags[i/8] |= 1 << (uint(i) % 8)
+		}
+	}
+
+	return tree
+}
+
+// ExtractMatches 校验部分Merkle树,返回Merkle根和匹配的交易ID
+func (tree *PartialMerkleTree) ExtractMatches() ([]byte, [][]byte, error) {
+
+	if tree.Total == 0 {
+		return nil, nil, errors.New("empty partial merkle tree")
+	}
+	if len(tree.Hashes) > int(tree.Total) {
+		return nil, nil, errors.New("more hashes than transactions")
+	}
+	if len(tree.Flags)*8 < len(tree.Hashes) {
+		return nil, nil, errors.New("not enough flag bits")
+	}
+
+	var bitsUsed, hashUsed int
+	var matches [][]byte
+	root, err := tree.traverseAndExtract(tree.height(), 0, &bitsUsed, &hashUsed, &matches)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 所有hash必须用完,标志位只允许末尾字节的填充
+	if hashUsed != len(tree.Hashes) {
+		return nil, nil, errors.New("unused hashes in partial merkle tree")
+	}
+	if (bitsUsed+7)/8 != len(tree.Flags) {
+		return nil, nil, errors.New("unused flag bits in partial merkle tree")
+	}
+
+	return root, matches, nil
+}
+
+// 树的高度
+func (tree *PartialMerkleTree) height() uint {
+	var height uint
+	for tree.width(height) > 1 {
+		height++
+	}
+	return height
+}
+
+// 指定高度的节点数量
+func (tree *PartialMerkleTree) width(height uint) uint32 {
+	return (tree.Total + (1 << height) - 1) >> height
+}
+
+// 计算指定节点的hash
+func (tree *PartialMerkleTree) calcHash(height uint, pos uint32, txids [][]byte) []byte {
+
+	if height == 0 {
+		return txids[pos]
+	}
+
+	left := tree.calcHash(height-1, pos*2, txids)
+	right := left
+	if pos*2+1 < tree.width(height-1) {
+		right = tree.calcHash(height-1, pos*2+1, txids)
+	}
+
+	return hashMerkleBranches(left, right)
+}
+
+// 深度优先构造部分Merkle树
+func (tree *PartialMerkleTree) traverseAndBuild(height uint, pos uint32, txids [][]byte, matches []bool, bits *[]bool) {
+
+	// 该节点下是否存在匹配的交易
+	parentOfMatch := false
+	for p := pos << height; p < (pos+1)<<height && p < tree.Total; p++ {
+		if matches[p] {
+			parentOfMatch = true
+			break
+		}
+	}
+	*bits = append(*bits, parentOfMatch)
+
+	// 叶子节点或没有匹配的子树,直接保存hash
+	if height == 0 || !parentOfMatch {
+		tree.Hashes = append(tree.Hashes, tree.calcHash(height, pos, txids))
+		return
+	}
+
+	tree.traverseAndBuild(height-1, pos*2, txids, matches, bits)
+	if pos*2+1 < tree.width(height-1) {
+		tree.traverseAndBuild(height-1, pos*2+1, txids, matches, bits)
+	}
+}
+
+// 深度优先还原Merkle根
+func (tree *PartialMerkleTree) traverseAndExtract(height uint, pos uint32, bitsUsed *int, hashUsed *int, matches *[][]byte) ([]byte, error) {
+
+	if *bitsUsed >= len(tree.Flags)*8 {
+		return nil, errors.New("partial merkle tree overflowed flag bits")
+	}
+	parentOfMatch := tree.Flags[*bitsUsed/8]&(1<<(uint(*bitsUsed)%8)) != 0
+	*bitsUsed++
+
+	if height == 0 || !parentOfMatch {
+		if *hashUsed >= len(tree.Hashes) {
+			return nil, errors.New("partial merkle tree overflowed hashes")
+		}
+		hash := tree.Hashes[*hashUsed]
+		*hashUsed++
+		if height == 0 && parentOfMatch {
+			*matches = append(*matches, hash)
+		}
+		return hash, nil
+	}
+
+	left, err := tree.traverseAndExtract(height-1, pos*2, bitsUsed, hashUsed, matches)
+	if err != nil {
+		return nil, err
+	}
+	right := left
+	if pos*2+1 < tree.width(height-1) {
+		right, err = tree.traverseAndExtract(height-1, pos*2+1, bitsUsed, hashUsed, matches)
+		if err != nil {
+			return nil, err
+		}
+		// 左右相同说明交易被重复,拒绝
+		if bytes.Equal(left, right) {
+			return nil, errors.New("duplicate hashes in partial merkle tree")
+		}
+	}
+
+	return hashMerkleBranches(left, right), nil
+}
@@ -71,6 +71,12 @@ func (p *POW) VerifyBlock(block *Block) bool {
 		}
 	}
 
+	// 验证Merkle根
+	if !bytes.Equal(CalcMerkleRoot(block.Transactions), block.MerkleRoot) {
+		fmt.Println("err MerkleRoot")
+		return false
+	}
+
 	// 验证区块Hash
 	blockHash := CalcBlockHash(block)
 	result := bytes.Equal(blockHash, block.Hash)
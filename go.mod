@@ -1,8 +1,9 @@
 module github.com/Alan-333333/simple-blockchain
 
-go 1.18
+go 1.24
 
 require (
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.3.0
 	github.com/stretchr/testify v1.8.1
 	golang.org/x/crypto v0.12.0
 )
@@ -11,5 +12,4 @@ require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
-
 )
@@ -2,14 +2,20 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	blockchain "github.com/Alan-333333/simple-blockchain/block/chain"
 	"github.com/Alan-333333/simple-blockchain/network/p2p"
+	"github.com/Alan-333333/simple-blockchain/signer"
 	"github.com/Alan-333333/simple-blockchain/transaction"
+	"github.com/Alan-333333/simple-blockchain/utils"
 	"github.com/Alan-333333/simple-blockchain/wallet"
 )
 
@@ -32,13 +38,46 @@ func printUsage() {
 
 	// Print wallet related commands
 	fmt.Println("Wallet Commands:")
-	fmt.Println("  createWallet - Create a new wallet")
+	fmt.Println("  createWallet [passphrase] [p256|secp256k1|ed25519] - Create a new wallet encrypted with passphrase")
+	fmt.Println("  unlockWallet [address] [passphrase] [seconds|-noTimeout] - Unlock a wallet for signing")
+	fmt.Println("  lockWallet [address] - Lock an unlocked wallet")
+	fmt.Println("  changePassphrase [address] [old] [new] - Change wallet passphrase")
+	fmt.Println("  createHDWallet [passphrase] - Create an HD wallet and print its mnemonic")
+	fmt.Println("  restoreHDWallet [passphrase] [mnemonic...] - Restore an HD wallet from its mnemonic")
+	fmt.Println("  newAddress [passphrase] - Derive the next HD wallet receive address")
+	fmt.Println("  splitWallet [address] [shares] [threshold] - Split the key of an unlocked wallet into Shamir backup shares")
+	fmt.Println("  splitHDWallet [passphrase] [shares] [threshold] - Split the HD wallet seed into Shamir backup shares")
+	fmt.Println("  recoverWallet [passphrase] [share...] - Recover a wallet from threshold many backup shares")
+	fmt.Println("  recoverHDWallet [passphrase] [share...] - Recover the HD wallet from threshold many backup shares")
 	fmt.Println("  getWalletBalance [address] - Get balance for a wallet")
 	fmt.Println("  addWalletBalance [address] [amount] - Add balance to a wallet")
+	fmt.Println("  watchAddress [address|[type:]pubkey] - Add a watch-only wallet for an address or hex public key")
+	fmt.Println("  signMessage [address] [message] - Sign a message with an unlocked wallet to prove address ownership")
+	fmt.Println("  verifyMessage [address] [signature] [message] - Verify a signed message against an address")
+	fmt.Println("  useSigner [address] [socket] - Sign transactions of a wallet with the signer daemon on a unix socket")
+	fmt.Println("  removeSigner [address] - Stop using the signer daemon for a wallet")
+	fmt.Println("  exportKey [address] - Print the private key of an unlocked wallet in WIF")
+	fmt.Println("  exportKeyPEM [address] [passphrase] [file] - Write the private key of an unlocked wallet as encrypted PEM")
+	fmt.Println("  exportPublicKey [address] - Print the public key and addresses of a wallet")
+	fmt.Println("  importKey [wif] [passphrase] [-rescan] - Import a WIF private key into a new encrypted wallet")
+	fmt.Println("  importKeyPEM [file] [pem passphrase] [passphrase] [-rescan] - Import an encrypted PEM private key")
+	fmt.Println("  getHistory [address] - Print confirmed transactions of an address")
+	fmt.Println("  bech32Address [address] - Print the Bech32 form of an address")
+	fmt.Println("  listTransactions [address] - Rescan the chain and print wallet transactions with confirmations")
+	fmt.Println("  setLabel [address|txid] [label] - Label an address or transaction, empty label removes it")
+	fmt.Println("  addContact [name] [address] - Add a named contact to the address book")
+	fmt.Println("  removeContact [name] - Remove a contact from the address book")
+	fmt.Println("  listContacts - Print the address book")
 
 	// Print transaction related commands
 	fmt.Println("Transaction Commands:")
-	fmt.Println("  sendTransaction -from [address] -to [address] -amount [amount] - Send a transaction")
+	fmt.Println("  sendTransaction -from [address] -to [address|contact] -amount [amount|max] [-fee [amount]] [-subtractFee] [-replaceable] - Send a transaction")
+	fmt.Println("  bumpFee [txid] [fee] - Replace a pending transaction with a higher fee")
+	fmt.Println("  createUnsignedTx -from [address] -to [address|contact] -amount [amount|max] [-fee [amount]] [-subtractFee] [-replaceable] -out [file] - Write an unsigned transaction file for offline signing")
+	fmt.Println("  decodeTxFile [file] - Verify and print a transaction file")
+	fmt.Println("  signTxFile [file] - Sign a transaction file with an unlocked wallet")
+	fmt.Println("  broadcastTxFile [file] - Broadcast a signed transaction file")
+	fmt.Println("  estimateFee [blocks] - Estimate the fee rate to confirm within blocks")
 
 	// Print node related commands
 	fmt.Println("Node Commands:")
@@ -52,15 +91,39 @@ func printUsage() {
 // main is the entry point of the program
 func main() {
 
-	// Initialize blockchain
+	// Load the saved blockchain before anything reads chain state.
+	// A chain that fails to load is left on disk untouched
 	pow := &blockchain.POW{}
-	bc := blockchain.NewBlockchain(pow)
+	bc, err := blockchain.LoadBlockchain(pow)
+	if err != nil {
+		fmt.Println("load blockchain failed:", err)
+		os.Exit(1)
+	}
 	txPool := transaction.NewTxPool()
-	bc.Save()
+	txPool.SetChainState(bc)
+
+	// Reload pending transactions saved on last shutdown, checked
+	// against the loaded chain
+	loaded, err := txPool.Load()
+	if err != nil {
+		fmt.Println("load mempool failed:", err)
+	}
+	fmt.Println("loaded mempool transactions:", loaded)
 
 	// Start mining
 	go bc.Mine(txPool)
 
+	// Expire old mempool transactions in background
+	go txPool.RunExpiry(time.Minute)
+	go printMempoolEvents(txPool)
+
+	// Start fee estimator
+	estimator := transaction.NewFeeEstimator()
+	if err := estimator.Load(); err != nil {
+		fmt.Println("load fee estimates failed:", err)
+	}
+	go estimator.Watch(txPool)
+
 	// Parse command line args
 	port := 3000
 	if len(os.Args) > 1 {
@@ -77,9 +140,18 @@ func main() {
 	// Print usage
 	printUsage()
 
-	// Block main thread
-	forever := make(chan bool)
-	<-forever
+	// Block main thread until shutdown
+	shutdown := make(chan os.Signal, 1)
+	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
+	<-shutdown
+
+	// Save pending transactions and fee estimates for next start
+	if err := txPool.Save(); err != nil {
+		fmt.Println("save mempool failed:", err)
+	}
+	if err := estimator.Save(); err != nil {
+		fmt.Println("save fee estimates failed:", err)
+	}
 }
 
 // printBlockchain prints all blocks in the blockchain
@@ -127,6 +199,10 @@ func startCLI(bc *blockchain.Blockchain, txPool *transaction.TxPool, node *p2p.N
 
 			// Print block by hash
 		case "printBlock":
+			if err := checkParams(args, 1, "[hash]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
 			// parse hash by args.params
 			hash := args.params[0]
 			// get block by hash
@@ -160,93 +236,728 @@ func startCLI(bc *blockchain.Blockchain, txPool *transaction.TxPool, node *p2p.N
 
 			// Create new wallet
 		case "createWallet":
+			if err := checkParams(args, 1, "[passphrase] [p256|secp256k1|ed25519]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
+
+			// Key type defaults to P-256
+			keyType := utils.DEFAULT_KEY_TYPE
+			if len(args.params) > 1 {
+				t, err := utils.ParseKeyType(args.params[1])
+				if err != nil {
+					fmt.Println(err)
+					continue
+				}
+				keyType = t
+			}
+
 			// Create new wallet
-			wallet := wallet.NewWallet()
+			wallet, err := wallet.NewWalletOfType(keyType)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+
+			// Encrypt private key with passphrase
+			if err := wallet.Encrypt(args.params[0]); err != nil {
+				fmt.Println(err)
+				continue
+			}
 
 			// Save updated wallet
-			wallet.Save()
+			if err := wallet.Save(); err != nil {
+				fmt.Println(err)
+				continue
+			}
 
-			// Broadcast updated wallet
-			node.BroadcastWallet(wallet)
+			// Announce public key to network
+			if err := node.AnnounceWallet(wallet); err != nil {
+				fmt.Println(err)
+			}
 
 			// Print success message
 			fmt.Println("success wallet address:", wallet.Address)
+
+			// Unlock wallet for a limited time
+		case "unlockWallet":
+			if err := checkParams(args, 2, "[address] [passphrase] [seconds|-noTimeout]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			timeout, err := parseUnlockTimeout(args)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+
+			if _, err := wallet.Unlock(args.params[0], args.params[1], timeout); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			printSuccess()
+
+			// Lock wallet
+		case "lockWallet":
+			if err := checkParams(args, 1, "[address]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			wallet.Lock(args.params[0])
+			printSuccess()
+
+			// Change wallet passphrase
+		case "changePassphrase":
+			if err := checkParams(args, 3, "[address] [old] [new]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			if err := wallet.ChangePassphrase(args.params[0], args.params[1], args.params[2]); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			printSuccess()
+
+			// Create HD wallet
+		case "createHDWallet":
+			if err := checkParams(args, 1, "[passphrase]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
+
+			_, mnemonic, err := wallet.CreateHDWallet(args.params[0])
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+
+			// Print mnemonic for backup
+			fmt.Println("success mnemonic:", mnemonic)
+
+			// Restore HD wallet from mnemonic
+		case "restoreHDWallet":
+			if err := checkParams(args, 2, "[passphrase] [mnemonic...]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
+
+			mnemonic := strings.Join(args.params[1:], " ")
+			hd, err := wallet.RestoreHDWallet(mnemonic, args.params[0], bc.IsAddressUsed)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			fmt.Println("success restored addresses:", len(hd.Receive)+len(hd.Change))
+
+			// Split a wallet key into Shamir shares
+		case "splitWallet":
+			if err := checkParams(args, 3, "[address] [shares] [threshold]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			w := wallet.GetwalletByAddress(args.params[0])
+			if w == nil {
+				fmt.Println(wallet.ErrWalletNotFound)
+				continue
+			}
+			n, m, err := parseShareCounts(args.params[1], args.params[2])
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			shares, err := w.SplitKey(n, m)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			printShares(shares, m)
+
+			// Split the HD wallet seed into Shamir shares
+		case "splitHDWallet":
+			if err := checkParams(args, 3, "[passphrase] [shares] [threshold]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			hd, err := wallet.LoadHDWallet()
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			n, m, err := parseShareCounts(args.params[1], args.params[2])
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			shares, err := hd.SplitSeed(args.params[0], n, m)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			printShares(shares, m)
+
+			// Recover a wallet key from Shamir shares
+		case "recoverWallet":
+			if err := checkParams(args, 2, "[passphrase] [share...]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			w, err := wallet.RecoverWallet(args.params[1:], args.params[0])
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			fmt.Println("success recovered address:", w.Address)
+
+			// Recover the HD wallet from Shamir shares of its seed
+		case "recoverHDWallet":
+			if err := checkParams(args, 2, "[passphrase] [share...]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			hd, err := wallet.RecoverHDWallet(args.params[1:], args.params[0], bc.IsAddressUsed)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			fmt.Println("success restored addresses:", len(hd.Receive)+len(hd.Change))
+
+			// Derive next HD receive address
+		case "newAddress":
+			if err := checkParams(args, 1, "[passphrase]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			hd, err := wallet.LoadHDWallet()
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+
+			w, err := hd.NewReceiveAddress(args.params[0], bc.IsAddressUsed)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+
+			// Announce public key to network
+			if err := node.AnnounceWallet(w); err != nil {
+				fmt.Println(err)
+			}
+			fmt.Println("success wallet address:", w.Address)
+
 		case "connectNode":
+			if err := checkParams(args, 2, "[IP] [port]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
 			ip := args.params[0]
 			portStr := args.params[1]
 
-			port, _ := strconv.Atoi(portStr)
+			port, err := strconv.Atoi(portStr)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
 			go node.Connect(ip, port)
 
 			fmt.Println("success")
 
 			// Get wallet balance
 		case "getWalletBalance":
+			if err := checkParams(args, 1, "[address]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
 			// Parse wallet address
 			address := args.params[0]
 			// Get balance
 			balance := wallet.GetAddressBalance(address)
+
+			// Watch-only wallets track balance from the chain
+			if w := wallet.GetwalletByAddress(address); w != nil && w.IsWatchOnly() {
+				balance = bc.GetBalance(address)
+				w.UpdateWalletBalance(balance)
+				w.Save()
+			}
 			// Print balance
 			fmt.Println("success wallet balance:", balance)
 
+			// Add watch-only wallet
+		case "watchAddress":
+			if err := checkParams(args, 1, "[address|[type:]pubkey]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			address := args.params[0]
+			pubKey, err := wallet.ParsePublicKeyHex(address)
+			if err == nil {
+				address = ""
+			}
+
+			w, err := wallet.NewWatchOnlyWallet(address, pubKey)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			w.UpdateWalletBalance(bc.GetBalance(w.Address))
+			if err := w.Save(); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			fmt.Println("success watching address:", w.Address)
+
+			// Sign a message to prove ownership of an address
+		case "signMessage":
+			if err := checkParams(args, 2, "[address] [message]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			w := wallet.GetwalletByAddress(args.params[0])
+			if w == nil {
+				fmt.Println(wallet.ErrWalletNotFound)
+				continue
+			}
+			signature, err := w.SignMessage(strings.Join(args.params[1:], " "))
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			fmt.Println("success signature:", signature)
+
+			// Verify a message signature against an address
+		case "verifyMessage":
+			if err := checkParams(args, 2, "[address] [signature] [message]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			if err := utils.VerifyMessage(args.params[0], strings.Join(args.params[2:], " "), args.params[1]); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			fmt.Println("success signature valid")
+
+			// Sign transactions of a wallet with a remote signer
+		case "useSigner":
+			if err := checkParams(args, 2, "[address] [socket]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			if wallet.GetwalletByAddress(args.params[0]) == nil {
+				fmt.Println(wallet.ErrWalletNotFound)
+				continue
+			}
+			remote, err := signer.NewRemoteSigner(args.params[1], args.params[0])
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			wallet.UseSigner(remote)
+			fmt.Println("success using signer:", args.params[1])
+
+			// Stop using a remote signer
+		case "removeSigner":
+			if err := checkParams(args, 1, "[address]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			wallet.RemoveSigner(args.params[0])
+			fmt.Println("success")
+
+			// Export private key as WIF
+		case "exportKey":
+			if err := checkParams(args, 1, "[address]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			w := wallet.GetwalletByAddress(args.params[0])
+			if w == nil {
+				fmt.Println(wallet.ErrWalletNotFound)
+				continue
+			}
+			wif, err := w.ExportWIF()
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			fmt.Println("success private key:", wif)
+
+			// Export private key as encrypted PEM
+		case "exportKeyPEM":
+			if err := checkParams(args, 3, "[address] [passphrase] [file]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			w := wallet.GetwalletByAddress(args.params[0])
+			if w == nil {
+				fmt.Println(wallet.ErrWalletNotFound)
+				continue
+			}
+			data, err := w.ExportPEM(args.params[1])
+			if err == nil {
+				err = os.WriteFile(args.params[2], data, 0600)
+			}
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			fmt.Println("success exported key:", args.params[2])
+
+			// Export public key and addresses
+		case "exportPublicKey":
+			if err := checkParams(args, 1, "[address]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			w := wallet.GetwalletByAddress(args.params[0])
+			if w == nil {
+				fmt.Println(wallet.ErrWalletNotFound)
+				continue
+			}
+			pubKey, err := w.ExportPublicKey()
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			bech32, err := utils.PubKeyToBech32Addr(w.PublicKey, utils.NetworkHRP)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			fmt.Println("success public key:", pubKey)
+			fmt.Println("address:", w.Address)
+			fmt.Println("bech32 address:", bech32)
+
+			// Import WIF private key
+		case "importKey":
+			if err := checkParams(args, 2, "[wif] [passphrase] [-rescan]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			w, err := wallet.ImportWIF(args.params[0], args.params[1])
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			if len(args.params) > 2 && args.params[2] == "-rescan" {
+				rescanWallet(bc, w)
+			}
+			fmt.Println("success imported address:", w.Address)
+
+			// Import encrypted PEM private key
+		case "importKeyPEM":
+			if err := checkParams(args, 3, "[file] [pem passphrase] [passphrase] [-rescan]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			data, err := os.ReadFile(args.params[0])
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			w, err := wallet.ImportPEM(data, args.params[1], args.params[2])
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			if len(args.params) > 3 && args.params[3] == "-rescan" {
+				rescanWallet(bc, w)
+			}
+			fmt.Println("success imported address:", w.Address)
+
+			// Print Bech32 form of address
+		case "bech32Address":
+			if err := checkParams(args, 1, "[address]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			keyType, pubKeyHash, err := utils.DecodeAddress(args.params[0])
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			address, err := utils.PubKeyHashToBech32Addr(keyType, pubKeyHash, utils.NetworkHRP)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			fmt.Println("success bech32 address:", address)
+
+			// Print address history
+		case "getHistory":
+			if err := checkParams(args, 1, "[address]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			for _, entry := range bc.GetAddressHistory(args.params[0]) {
+				tx := entry.Tx
+				fmt.Printf("height %d txid %s from %s to %s value %s fee %s\n", entry.Height, tx.IDHex(), tx.Sender, tx.Recipient, tx.Value, tx.Fee)
+			}
+
+			// Rebuild and print wallet history
+		case "listTransactions":
+			if err := checkParams(args, 1, "[address]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			address := args.params[0]
+			history, err := wallet.Rescan(address, walletChain{bc})
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			labels, err := wallet.LoadLabels()
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			if label := labels.AddressLabel(address); label != "" {
+				fmt.Println("label:", label)
+			}
+			for _, entry := range history {
+				fmt.Printf("%s %d confirmations txid %s from %s to %s amount %s fee %s %s\n",
+					entry.Status(), entry.Confirmations(bc.GetHeight()), entry.TxID, entry.Sender, entry.Recipient,
+					entry.Amount(address), entry.Fee, labels.TxLabel(entry.TxID))
+			}
+
+			// Label an address or transaction
+		case "setLabel":
+			if err := checkParams(args, 1, "[address|txid] [label]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			labels, err := wallet.LoadLabels()
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			target, label := args.params[0], strings.Join(args.params[1:], " ")
+			if utils.ValidateAddress(target) == nil {
+				err = labels.SetAddressLabel(target, label)
+			} else {
+				err = labels.SetTxLabel(target, label)
+			}
+			if err == nil {
+				err = labels.Save()
+			}
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			printSuccess()
+
+			// Add a contact to the address book
+		case "addContact":
+			if err := checkParams(args, 2, "[name] [address]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			book, err := wallet.LoadAddressBook()
+			if err == nil {
+				err = book.Add(args.params[0], args.params[1])
+			}
+			if err == nil {
+				err = book.Save()
+			}
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			printSuccess()
+
+			// Remove a contact from the address book
+		case "removeContact":
+			if err := checkParams(args, 1, "[name]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			book, err := wallet.LoadAddressBook()
+			if err == nil {
+				err = book.Remove(args.params[0])
+			}
+			if err == nil {
+				err = book.Save()
+			}
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			printSuccess()
+
+			// Print the address book
+		case "listContacts":
+			book, err := wallet.LoadAddressBook()
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			for _, contact := range book.List() {
+				fmt.Println(contact.Name, contact.Address)
+			}
+
 			// Add balance to wallet
 		case "addWalletBalance":
+			if err := checkParams(args, 2, "[address] [amount]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
 			address := args.params[0]
-			amount := args.params[1]
-			amountFloat, _ := strconv.ParseFloat(amount, 32)
+			amount, err := transaction.ParseAmount(args.params[1])
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
 
 			// Get wallet
 			wallet := wallet.GetwalletByAddress(address)
+			if wallet == nil {
+				fmt.Println("wallet not found")
+				continue
+			}
 			// Update balance
-			balance := wallet.Balance + amountFloat
+			balance, err := wallet.Balance.Add(amount)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
 			wallet.UpdateWalletBalance(balance)
 
 			// Save updated wallet
 			wallet.Save()
 
-			// Broadcast updated wallet
-			node.BroadcastWallet(wallet)
-
 			// Print success message
 			printSuccess()
 
 			// Send transaction
 		case "sendTransaction":
-			// Parse transaction parameters
-			fromAddress := parseFromAddress(args)
-			toAddress := parseToAddress(args)
-			amount := parseAmount(args)
+			tx, err := sendTransaction(args, txPool, node)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
 
-			// Get sender and recipient wallets
-			senderWallet := wallet.GetwalletByAddress(fromAddress)
-			recipientWallet := wallet.GetwalletByAddress(toAddress)
+			// Print success message
+			fmt.Println("success txid:", tx.IDHex(), "fee:", tx.Fee)
 
-			// Create new transaction
-			tx := transaction.NewTransaction(senderWallet.Address, recipientWallet.Address, float32(amount))
+			// Create an unsigned transaction file for offline signing
+		case "createUnsignedTx":
+			if err := checkParams(args, 6, "-from [address] -to [address|contact] -amount [amount|max] [-fee [amount]] [-subtractFee] [-replaceable] -out [file]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			path := flagValue(args, "-out")
+			if path == "" {
+				fmt.Println("output file required")
+				continue
+			}
 
-			// Sign the transaction
-			tx.Sign(senderWallet.PrivateKey)
+			// Sender may be a watch-only wallet, the key stays offline
+			senderWallet := wallet.GetwalletByAddress(parseFromAddress(args))
+			if senderWallet == nil {
+				fmt.Println(wallet.ErrWalletNotFound)
+				continue
+			}
+			tx, err := newTxFromArgs(args, senderWallet, txPool, node)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
 
-			// Add transaction to transaction pool
-			txPool.AddTx(tx)
+			partial := transaction.NewPartialTx(tx, senderWallet.Spendable(), bc.GetNonce(tx.Sender), bc.GetHeight())
+			if err := transaction.WritePartialTx(path, partial); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			fmt.Println("success unsigned transaction:", path)
 
-			// Broadcast transaction to network
-			node.BroadcastTx(tx)
+			// Print the content of a transaction file
+		case "decodeTxFile":
+			if err := checkParams(args, 1, "[file]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			partial, err := transaction.ReadPartialTx(args.params[0])
+			if err != nil {
+				fmt.Println("invalid transaction file:", err)
+				continue
+			}
+			printPartialTx(partial)
 
-			// Update wallet balances
-			senderWallet.Balance -= amount
-			recipientWallet.Balance += amount
+			// Sign a transaction file with an unlocked wallet
+		case "signTxFile":
+			if err := checkParams(args, 1, "[file]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			path := args.params[0]
+			partial, err := transaction.ReadPartialTx(path)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			senderWallet := wallet.GetwalletByAddress(partial.Tx.Sender)
+			if senderWallet == nil {
+				fmt.Println(wallet.ErrWalletNotFound)
+				continue
+			}
+			if err := senderWallet.SignPartialTx(partial); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			if err := transaction.WritePartialTx(path, partial); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			fmt.Println("success signed txid:", partial.Tx.IDHex())
 
-			// Save updated wallets
-			senderWallet.Save()
-			recipientWallet.Save()
+			// Broadcast a signed transaction file
+		case "broadcastTxFile":
+			if err := checkParams(args, 1, "[file]"); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			partial, err := transaction.ReadPartialTx(args.params[0])
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			if !partial.IsSigned() {
+				fmt.Println(transaction.ErrPartialTxPending)
+				continue
+			}
+			if err := submitTx(txPool, node, partial.Tx); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			fmt.Println("success txid:", partial.Tx.IDHex())
+
+			// Estimate fee rate
+		case "estimateFee":
+			target := transaction.DEFAULT_CONF_TARGET
+			if len(args.params) > 0 {
+				var err error
+				if target, err = strconv.Atoi(args.params[0]); err != nil {
+					fmt.Println(err)
+					continue
+				}
+			}
 
-			// Broadcast updated wallets to network
-			node.BroadcastWallet(senderWallet)
-			node.BroadcastWallet(recipientWallet)
+			estimate, err := node.EstimateFee(target, transaction.DEFAULT_FEE_CONFIDENCE)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			fmt.Printf("success fee rate: %s within %d blocks (confidence %.2f)\n", estimate.FeeRate, estimate.Target, estimate.Confidence)
+
+			// Replace a pending transaction with a higher fee
+		case "bumpFee":
+			tx, err := bumpFee(args, txPool, node)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
 
 			// Print success message
-			printSuccess()
+			fmt.Println("success txid:", tx.IDHex())
 
 		default:
 			printUsage()
@@ -254,11 +965,309 @@ func startCLI(bc *blockchain.Blockchain, txPool *transaction.TxPool, node *p2p.N
 	}
 }
 
+// printMempoolEvents prints transactions expired from the mempool
+func printMempoolEvents(txPool *transaction.TxPool) {
+	for event := range txPool.Subscribe(100) {
+		if event.Type == transaction.EventExpired {
+			fmt.Println("mempool transaction expired:", event.Tx.IDHex())
+		}
+	}
+}
+
+// sendTransaction creates, signs and submits a transaction from
+// sendTransaction arguments. The recipient may be any address or contact,
+// only wallets present locally have their balance and history updated.
+func sendTransaction(args Input, txPool *transaction.TxPool, node *p2p.Node) (*transaction.Transaction, error) {
+
+	if err := checkParams(args, 6, "-from [address] -to [address|contact] -amount [amount|max] [-fee [amount]] [-subtractFee] [-replaceable]"); err != nil {
+		return nil, err
+	}
+
+	// Get sender wallet
+	senderWallet := wallet.GetwalletByAddress(parseFromAddress(args))
+	if senderWallet == nil {
+		return nil, wallet.ErrWalletNotFound
+	}
+	if err := senderWallet.CanSignTx(); err != nil {
+		return nil, err
+	}
+
+	// Create new transaction
+	tx, err := newTxFromArgs(args, senderWallet, txPool, node)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sign the transaction, possibly by a remote signer
+	if err := senderWallet.SignTx(tx); err != nil {
+		return nil, err
+	}
+
+	// Add to transaction pool, broadcast and update wallets
+	if err := submitTx(txPool, node, tx); err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// newTxFromArgs creates an unsigned transaction from sendTransaction style
+// arguments, checking the sender's balance covers amount and fee
+func newTxFromArgs(args Input, senderWallet *wallet.Wallet, txPool *transaction.TxPool, node *p2p.Node) (*transaction.Transaction, error) {
+
+	// Recipient may be an address or a contact name
+	book, err := wallet.LoadAddressBook()
+	if err != nil {
+		return nil, err
+	}
+	toAddress, err := book.ResolveAddress(parseToAddress(args))
+	if err != nil {
+		return nil, err
+	}
+	fee, err := parseFee(args)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sending max spends the whole balance with the fee taken from the amount
+	sendMax := isSendMax(args)
+	var amount transaction.Amount
+	if sendMax {
+		amount = senderWallet.Spendable()
+	} else if amount, err = parseAmount(args); err != nil {
+		return nil, err
+	}
+
+	nonce := txPool.NextNonce(senderWallet.Address)
+	tx := transaction.NewTransaction(senderWallet.Address, toAddress, amount, fee, nonce)
+
+	// Replace-by-fee is opt-in
+	tx.Replaceable = hasFlag(args, "-replaceable")
+
+	// Default to the estimated fee
+	if !hasFee(args) {
+		tx.Fee = estimateTxFee(node, tx)
+	}
+
+	// Check the balance covers amount and fee
+	if err := senderWallet.FundTx(tx, sendMax || hasFlag(args, "-subtractFee")); err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// submitTx adds a signed transaction to the pool, broadcasts it and
+// records it in the history and balance of local wallets. The submission
+// fails before reaching the pool if the sender's balance can't cover it
+func submitTx(txPool *transaction.TxPool, node *p2p.Node, tx *transaction.Transaction) error {
+
+	// Work out the balance changes first so nothing is half applied
+	total, err := tx.Value.Add(tx.Fee)
+	if err != nil {
+		return err
+	}
+	sender := wallet.GetwalletByAddress(tx.Sender)
+	var senderBalance transaction.Amount
+	if sender != nil {
+		if senderBalance, err = sender.Balance.Sub(total); err != nil {
+			return wallet.ErrInsufficientFunds{Available: sender.Spendable(), Required: total}
+		}
+	}
+	recipient := wallet.GetwalletByAddress(tx.Recipient)
+	var recipientBalance transaction.Amount
+	if recipient != nil {
+		if recipientBalance, err = recipient.Balance.Add(tx.Value); err != nil {
+			return err
+		}
+	}
+
+	if err := txPool.AddTx(tx); err != nil {
+		return err
+	}
+	node.BroadcastTx(tx)
+
+	if sender != nil {
+		wallet.RecordTx(sender.Address, tx)
+		sender.Balance = senderBalance
+		sender.Save()
+	}
+	if recipient != nil {
+		wallet.RecordTx(recipient.Address, tx)
+		recipient.Balance = recipientBalance
+		recipient.Save()
+	}
+
+	return nil
+}
+
+// bumpFee replaces a pending replaceable transaction with one paying a
+// higher fee, charging the extra fee to the sender's wallet
+func bumpFee(args Input, txPool *transaction.TxPool, node *p2p.Node) (*transaction.Transaction, error) {
+
+	if err := checkParams(args, 2, "[txid] [fee]"); err != nil {
+		return nil, err
+	}
+
+	// Find pending transaction
+	pending := txPool.GetTxByID(args.params[0])
+	if pending == nil {
+		return nil, errors.New("transaction not found in mempool")
+	}
+	fee, err := transaction.ParseAmount(args.params[1])
+	if err != nil {
+		return nil, err
+	}
+
+	// Create replacement with the same nonce
+	senderWallet := wallet.GetwalletByAddress(pending.Sender)
+	if senderWallet == nil {
+		return nil, wallet.ErrWalletNotFound
+	}
+	// The balance must cover the extra fee
+	extra, err := fee.Sub(pending.Fee)
+	if err != nil {
+		return nil, transaction.ErrReplacementFee
+	}
+	balance, err := senderWallet.Balance.Sub(extra)
+	if err != nil {
+		return nil, wallet.ErrInsufficientFunds{Available: senderWallet.Spendable(), Required: extra}
+	}
+	tx := transaction.NewTransaction(pending.Sender, pending.Recipient, pending.Value, fee, pending.Nonce)
+	tx.Replaceable = pending.Replaceable
+	if err := senderWallet.SignTx(tx); err != nil {
+		return nil, err
+	}
+
+	// Replace in transaction pool
+	if err := txPool.AddTx(tx); err != nil {
+		return nil, err
+	}
+
+	// Broadcast replacement to network
+	node.BroadcastTx(tx)
+
+	// Replace original transaction in wallet history
+	wallet.RecordTx(tx.Sender, tx)
+
+	// Charge the extra fee
+	senderWallet.Balance = balance
+	senderWallet.Save()
+
+	return tx, nil
+}
+
+// printPartialTx prints a transaction file for inspection before signing
+func printPartialTx(p *transaction.PartialTx) {
+	tx := p.Tx
+	fmt.Println("network:", p.Network)
+	fmt.Println("from:", tx.Sender)
+	fmt.Println("to:", tx.Recipient)
+	fmt.Println("amount:", tx.Value)
+	fmt.Println("fee:", tx.Fee)
+	fmt.Println("nonce:", tx.Nonce, "chain nonce:", p.ChainNonce)
+	fmt.Println("sender balance:", p.Balance, "at height", p.Height)
+	fmt.Println("replaceable:", tx.Replaceable)
+	if p.IsSigned() {
+		fmt.Println("signed txid:", tx.IDHex())
+	} else {
+		fmt.Println("unsigned")
+	}
+}
+
+// rescanWallet updates an imported wallet's balance and history from the chain
+func rescanWallet(bc *blockchain.Blockchain, w *wallet.Wallet) {
+	w.UpdateWalletBalance(bc.GetBalance(w.Address))
+	if err := w.Save(); err != nil {
+		fmt.Println(err)
+		return
+	}
+	history, err := wallet.Rescan(w.Address, walletChain{bc})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("rescan found", len(history), "transactions, balance:", w.Balance)
+}
+
+// parseShareCounts parses the number of backup shares and the threshold
+func parseShareCounts(shares, threshold string) (int, int, error) {
+	n, err := strconv.Atoi(shares)
+	if err != nil {
+		return 0, 0, err
+	}
+	m, err := strconv.Atoi(threshold)
+	if err != nil {
+		return 0, 0, err
+	}
+	return n, m, nil
+}
+
+// printShares prints backup shares, each to be stored in a separate place
+func printShares(shares []string, threshold int) {
+	fmt.Println("success shares, any", threshold, "recover the wallet:")
+	for i, share := range shares {
+		fmt.Printf("%d: %s\n", i+1, share)
+	}
+}
+
+// walletChain adapts the blockchain to the view used by wallet rescans
+type walletChain struct {
+	bc *blockchain.Blockchain
+}
+
+func (c walletChain) GetNonce(address string) uint64 {
+	return c.bc.GetNonce(address)
+}
+
+func (c walletChain) GetConfirmedTxs(address string) []*wallet.HistoryEntry {
+	var history []*wallet.HistoryEntry
+	for _, entry := range c.bc.GetAddressHistory(address) {
+		history = append(history, wallet.NewHistoryEntry(entry.Tx, entry.Height, entry.BlockHash))
+	}
+	return history
+}
+
 // print success
 func printSuccess() {
 	fmt.Println("success")
 }
 
+// parseUnlockTimeout parses the optional unlock timeout in seconds,
+// defaulting to five minutes. Staying unlocked until lockWallet needs
+// the explicit -noTimeout flag so a typo can't leave a wallet unlocked
+func parseUnlockTimeout(args Input) (time.Duration, error) {
+	if len(args.params) < 3 {
+		return 5 * time.Minute, nil
+	}
+	if args.params[2] == "-noTimeout" {
+		return 0, nil
+	}
+
+	seconds, err := strconv.Atoi(args.params[2])
+	if err != nil {
+		return 0, err
+	}
+	if seconds <= 0 {
+		return 0, errors.New("unlock timeout must be positive, use -noTimeout to stay unlocked")
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// checkParams returns a usage error unless the first n parameters are
+// given and non-empty
+func checkParams(args Input, n int, usage string) error {
+	if len(args.params) < n {
+		return fmt.Errorf("usage: %s %s", args.command, usage)
+	}
+	for _, param := range args.params[:n] {
+		if param == "" {
+			return fmt.Errorf("usage: %s %s", args.command, usage)
+		}
+	}
+	return nil
+}
+
 // parse from address from input
 func parseFromAddress(args Input) string {
 	return args.params[1]
@@ -270,10 +1279,64 @@ func parseToAddress(args Input) string {
 }
 
 // parse amount from input
-func parseAmount(args Input) float64 {
+func parseAmount(args Input) (transaction.Amount, error) {
 	amountStr := args.params[5]
-	amountFloat, _ := strconv.ParseFloat(amountStr, 32)
-	return amountFloat
+	return transaction.ParseAmount(amountStr)
+}
+
+// check whether the amount is "max", sending the whole balance
+func isSendMax(args Input) bool {
+	return strings.EqualFold(args.params[5], "max")
+}
+
+// check whether an optional flag follows the required parameters
+func hasFlag(args Input, flag string) bool {
+	for _, param := range args.params[6:] {
+		if param == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// value following an optional flag, empty when the flag is missing
+func flagValue(args Input, flag string) string {
+	for i := 6; i+1 < len(args.params); i++ {
+		if args.params[i] == flag {
+			return args.params[i+1]
+		}
+	}
+	return ""
+}
+
+// check whether a fee was given in input
+func hasFee(args Input) bool {
+	return len(args.params) >= 8 && args.params[6] == "-fee"
+}
+
+// parse optional fee from input, defaults to zero
+func parseFee(args Input) (transaction.Amount, error) {
+	if !hasFee(args) {
+		return 0, nil
+	}
+	return transaction.ParseAmount(args.params[7])
+}
+
+// estimateTxFee returns the fee for tx at the estimated fee rate,
+// falling back to the default rate without enough data
+func estimateTxFee(node *p2p.Node, tx *transaction.Transaction) transaction.Amount {
+	rate := transaction.DEFAULT_FEE_RATE
+	estimate, err := node.EstimateFee(transaction.DEFAULT_CONF_TARGET, transaction.DEFAULT_FEE_CONFIDENCE)
+	if err == nil {
+		rate = estimate.FeeRate
+	}
+
+	// Signature length is not known before signing, assume the largest DER signature
+	size := len(tx.Serialize()) + transaction.MAX_SIGNATURE_LEN
+	if len(tx.PubKey) == 0 {
+		size += transaction.MAX_PUBKEY_LEN
+	}
+	return rate.FeeFor(size)
 }
 
 // parseInput parses user input into command and parameters
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Alan-333333/simple-blockchain/network/p2p"
+	"github.com/Alan-333333/simple-blockchain/transaction"
+	"github.com/Alan-333333/simple-blockchain/wallet"
+)
+
+// run in a temporary directory so wallet files stay out of the source tree
+func chdirTemp(t *testing.T) {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(dir) })
+}
+
+func TestSendToContact(t *testing.T) {
+
+	chdirTemp(t)
+
+	// Sender wallet with a balance, unlocked for signing
+	sender := wallet.NewWallet()
+	sender.UpdateWalletBalance(10 * transaction.COIN)
+	if err := sender.Encrypt("passphrase"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sender.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wallet.Unlock(sender.Address, "passphrase", 0); err != nil {
+		t.Fatal(err)
+	}
+	defer wallet.Lock(sender.Address)
+
+	// Contact whose address has no local wallet file
+	contact := wallet.NewWallet().Address
+	book, err := wallet.LoadAddressBook()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := book.Add("bob", contact); err != nil {
+		t.Fatal(err)
+	}
+	if err := book.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	args := Input{command: "sendTransaction", params: []string{"-from", sender.Address, "-to", "bob", "-amount", "1", "-fee", "0.001"}}
+	tx, err := sendTransaction(args, transaction.NewTxPool(), p2p.NewNode("127.0.0.1", 0))
+	if err != nil {
+		t.Fatalf("send to contact: %v", err)
+	}
+	if tx.Recipient != contact || !tx.IsValid() {
+		t.Errorf("transaction not sent to contact address")
+	}
+
+	// Only the local sender wallet is updated
+	if w := wallet.GetwalletByAddress(sender.Address); w.Balance != 10*transaction.COIN-transaction.COIN-100000 {
+		t.Errorf("sender balance %s", w.Balance)
+	}
+	if len(wallet.GetHistory(sender.Address)) != 1 {
+		t.Errorf("sender history not recorded")
+	}
+	if wallet.GetwalletByAddress(contact) != nil {
+		t.Errorf("wallet created for contact")
+	}
+}
+
+func TestSubmitTxOverspend(t *testing.T) {
+
+	chdirTemp(t)
+
+	// Local sender with less than the transaction spends, e.g. a
+	// transaction file signed elsewhere
+	sender := wallet.NewWallet()
+	sender.UpdateWalletBalance(transaction.COIN)
+	recipient := wallet.NewWallet()
+	tx := transaction.NewTransaction(sender.Address, recipient.Address, 5*transaction.COIN, 100000, 0)
+	if err := tx.Sign(sender.PrivateKey); err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range []*wallet.Wallet{sender, recipient} {
+		if err := w.Encrypt("passphrase"); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	txPool := transaction.NewTxPool()
+	size := txPool.Size()
+	if err := submitTx(txPool, p2p.NewNode("127.0.0.1", 0), tx); err == nil {
+		t.Fatal("overspending transaction submitted")
+	}
+
+	// Nothing is applied when the submission fails
+	if txPool.Size() != size || txPool.GetTxByID(tx.IDHex()) != nil {
+		t.Errorf("overspending transaction added to pool")
+	}
+	if w := wallet.GetwalletByAddress(sender.Address); w.Balance != transaction.COIN {
+		t.Errorf("sender balance %s", w.Balance)
+	}
+	if w := wallet.GetwalletByAddress(recipient.Address); w.Balance != 0 {
+		t.Errorf("recipient credited %s", w.Balance)
+	}
+}
+
+func TestReplaceableOptIn(t *testing.T) {
+
+	chdirTemp(t)
+
+	sender := wallet.NewWallet()
+	sender.UpdateWalletBalance(10 * transaction.COIN)
+	if err := sender.Encrypt("passphrase"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sender.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wallet.Unlock(sender.Address, "passphrase", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	defer wallet.Lock(sender.Address)
+
+	txPool := transaction.NewTxPool()
+	node := p2p.NewNode("127.0.0.1", 0)
+	recipient := wallet.NewWallet().Address
+	send := func(flags ...string) *transaction.Transaction {
+		params := append([]string{"-from", sender.Address, "-to", recipient, "-amount", "1", "-fee", "0.001"}, flags...)
+		tx, err := sendTransaction(Input{command: "sendTransaction", params: params}, txPool, node)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tx
+	}
+
+	// Transactions are final unless sent with -replaceable
+	final := send()
+	if final.Replaceable {
+		t.Errorf("transaction replaceable without -replaceable")
+	}
+	if _, err := bumpFee(Input{command: "bumpFee", params: []string{final.IDHex(), "0.01"}}, txPool, node); err == nil {
+		t.Errorf("bumped fee of a final transaction")
+	}
+
+	replaceable := send("-replaceable")
+	if !replaceable.Replaceable {
+		t.Fatalf("transaction not replaceable with -replaceable")
+	}
+	bumped, err := bumpFee(Input{command: "bumpFee", params: []string{replaceable.IDHex(), "0.01"}}, txPool, node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bumped.Nonce != replaceable.Nonce || txPool.GetTxByID(replaceable.IDHex()) != nil {
+		t.Errorf("transaction not replaced")
+	}
+}
+
+func TestBumpFeeArgs(t *testing.T) {
+
+	txPool := transaction.NewTxPool()
+	node := p2p.NewNode("127.0.0.1", 0)
+
+	// Missing or empty parameters give a usage error instead of panicking
+	for _, params := range [][]string{nil, {"txid"}, {"", "0.01"}} {
+		if _, err := bumpFee(Input{command: "bumpFee", params: params}, txPool, node); err == nil || !strings.HasPrefix(err.Error(), "usage:") {
+			t.Errorf("bumpFee %q got %v", params, err)
+		}
+	}
+}
+
+func TestParseUnlockTimeout(t *testing.T) {
+
+	unlock := func(params ...string) Input {
+		return Input{command: "unlockWallet", params: append([]string{"address", "passphrase"}, params...)}
+	}
+
+	if timeout, err := parseUnlockTimeout(unlock()); err != nil || timeout != 5*time.Minute {
+		t.Errorf("default timeout %v %v", timeout, err)
+	}
+	if timeout, err := parseUnlockTimeout(unlock("30")); err != nil || timeout != 30*time.Second {
+		t.Errorf("timeout %v %v", timeout, err)
+	}
+	if timeout, err := parseUnlockTimeout(unlock("-noTimeout")); err != nil || timeout != 0 {
+		t.Errorf("no timeout %v %v", timeout, err)
+	}
+
+	// Typos and non-positive timeouts never mean staying unlocked
+	for _, seconds := range []string{"3O", "", "0", "-5"} {
+		if _, err := parseUnlockTimeout(unlock(seconds)); err == nil {
+			t.Errorf("timeout %q accepted", seconds)
+		}
+	}
+}
+
+func TestCheckParams(t *testing.T) {
+
+	args := Input{command: "changePassphrase", params: []string{"address", "old", ""}}
+	if err := checkParams(args, 2, "[address] [old] [new]"); err != nil {
+		t.Errorf("given parameters rejected: %v", err)
+	}
+
+	// Missing and empty parameters, e.g. an empty passphrase
+	if err := checkParams(args, 3, "[address] [old] [new]"); err == nil {
+		t.Errorf("empty parameter accepted")
+	}
+	if err := checkParams(args, 4, "[a] [b] [c] [d]"); err == nil {
+		t.Errorf("missing parameter accepted")
+	}
+	if _, err := sendTransaction(Input{command: "sendTransaction", params: []string{"-from"}}, transaction.NewTxPool(), p2p.NewNode("127.0.0.1", 0)); err == nil {
+		t.Errorf("sendTransaction without parameters accepted")
+	}
+}
+
+func TestSendInsufficientFunds(t *testing.T) {
+
+	chdirTemp(t)
+
+	sender := wallet.NewWallet()
+	sender.UpdateWalletBalance(transaction.COIN)
+	if err := sender.Encrypt("passphrase"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sender.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wallet.Unlock(sender.Address, "passphrase", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	defer wallet.Lock(sender.Address)
+
+	txPool := transaction.NewTxPool()
+	size := txPool.Size()
+	node := p2p.NewNode("127.0.0.1", 0)
+	recipient := wallet.NewWallet().Address
+
+	// Amount plus fee above the balance gives a clear error
+	args := Input{command: "sendTransaction", params: []string{"-from", sender.Address, "-to", recipient, "-amount", "1", "-fee", "0.001"}}
+	_, err := sendTransaction(args, txPool, node)
+	if e, ok := err.(wallet.ErrInsufficientFunds); !ok || e.Available != transaction.COIN || e.Required != transaction.COIN+100000 {
+		t.Errorf("insufficient funds got %v", err)
+	}
+	if txPool.Size() != size {
+		t.Errorf("unfunded transaction added to pool")
+	}
+
+	// Sending max takes the fee from the amount
+	args.params[5] = "max"
+	tx, err := sendTransaction(args, txPool, node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Value != transaction.COIN-100000 {
+		t.Errorf("send max value %s", tx.Value)
+	}
+	if w := wallet.GetwalletByAddress(sender.Address); w.Balance != 0 {
+		t.Errorf("sender balance after send max %s", w.Balance)
+	}
+}
@@ -0,0 +1,306 @@
+package p2p
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	blockchain "github.com/Alan-333333/simple-blockchain/block/chain"
+	"github.com/Alan-333333/simple-blockchain/transaction"
+)
+
+// 布隆过滤器的大小限制,与BIP37一致
+const (
+	MaxFilterSize    = 36000
+	MaxHashFuncs     = 50
+	hashSeedFactor   = 0xFBA4C795
+	ln2Squared       = math.Ln2 * math.Ln2
+	maxFilterAddSize = 520
+)
+
+// 每个peer未完成的过滤区块请求数量上限
+const MAX_MERKLE_BLOCK_REQUESTS = 16
+
+// 过滤区块请求的超时时间,超时后不再接受该区块
+const MERKLE_BLOCK_TIMEOUT = 30 * time.Second
+
+// BloomFilter BIP37风格的布隆过滤器,匹配地址和交易ID
+type BloomFilter struct {
+	Data      []byte
+	HashFuncs uint32
+	Tweak     uint32
+
+	lock sync.RWMutex
+}
+
+// NewBloomFilter 根据元素数量和误报率创建布隆过滤器
+func NewBloomFilter(elements int, fpRate float64, tweak uint32) *BloomFilter {
+
+	if elements < 1 {
+		elements = 1
+	}
+	if fpRate <= 0 {
+		fpRate = 1e-6
+	}
+	if fpRate > 1 {
+		fpRate = 1
+	}
+
+	// 1. 计算过滤器字节数
+	size := int(-1 / ln2Squared * float64(elements) * math.Log(fpRate) / 8)
+	if size < 1 {
+		size = 1
+	}
+	if size > MaxFilterSize {
+		size = MaxFilterSize
+	}
+
+	// 2. 计算hash函数个数
+	hashFuncs := uint32(float64(size*8) / float64(elements) * math.Ln2)
+	if hashFuncs < 1 {
+		hashFuncs = 1
+	}
+	if hashFuncs > MaxHashFuncs {
+		hashFuncs = MaxHashFuncs
+	}
+
+	return &BloomFilter{
+		Data:      make([]byte, size),
+		HashFuncs: hashFuncs,
+		Tweak:     tweak,
+	}
+}
+
+// IsValid 检查过滤器参数是否超出限制
+func (f *BloomFilter) IsValid() bool {
+	return len(f.Data) > 0 && len(f.Data) <= MaxFilterSize &&
+		f.HashFuncs > 0 && f.HashFuncs <= MaxHashFuncs
+}
+
+// 计算第n个hash函数对应的位
+func (f *BloomFilter) hash(n uint32, data []byte) uint32 {
+	seed := n*hashSeedFactor + f.Tweak
+	return murmurHash3(seed, data) % (uint32(len(f.Data)) * 8)
+}
+
+// Add 向过滤器添加元素
+func (f *BloomFilter) Add(data []byte) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for i := uint32(0); i < f.HashFuncs; i++ {
+		idx := f.hash(i, data)
+		f.Data[idx>>3] |= 1 << (idx & 7)
+	}
+}
+
+// AddAddress 向过滤器添加地址
+func (f *BloomFilter) AddAddress(address string) {
+	f.Add([]byte(address))
+}
+
+// Contains 判断元素是否可能在过滤器中
+func (f *BloomFilter) Contains(data []byte) bool {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	for i := uint32(0); i < f.HashFuncs; i++ {
+		idx := f.hash(i, data)
+		if f.Data[idx>>3]&(1<<(idx&7)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchTx 判断交易是否匹配过滤器,匹配交易ID、发送方或接收方地址、发送方公钥
+func (f *BloomFilter) MatchTx(tx *transaction.Transaction) bool {
+	return f.Contains(tx.ID()) ||
+		f.Contains([]byte(tx.Sender)) ||
+		f.Contains([]byte(tx.Recipient)) ||
+		(len(tx.PubKey) > 0 && f.Contains(tx.PubKey))
+}
+
+// MerkleBlock 过滤后的区块,只包含匹配的交易和部分Merkle树
+type MerkleBlock struct {
+	Header       *blockchain.Block
+	Tree         *blockchain.PartialMerkleTree
+	Transactions []*transaction.Transaction
+}
+
+// BlockInv 向加载了过滤器的peer通告新区块,由对端决定是否请求过滤后的区块
+type BlockInv struct {
+	BlockHash []byte
+}
+
+// GetMerkleBlock 请求过滤后的区块
+type GetMerkleBlock struct {
+	BlockHash []byte
+}
+
+// NewMerkleBlock 使用过滤器构造过滤后的区块
+func NewMerkleBlock(block *blockchain.Block, filter *BloomFilter) *MerkleBlock {
+
+	txids := make([][]byte, len(block.Transactions))
+	matches := make([]bool, len(block.Transactions))
+	matched := []*transaction.Transaction{}
+
+	for i, tx := range block.Transactions {
+		txids[i] = tx.ID()
+		if filter.MatchTx(tx) {
+			matches[i] = true
+			matched = append(matched, tx)
+		}
+	}
+
+	return &MerkleBlock{
+		Header:       block.Header(),
+		Tree:         blockchain.NewPartialMerkleTree(txids, matches),
+		Transactions: matched,
+	}
+}
+
+// Verify 校验过滤后的区块,确保交易都在区块头的Merkle根下
+func (mb *MerkleBlock) Verify() error {
+
+	if mb.Header == nil || mb.Tree == nil {
+		return errors.New("incomplete merkle block")
+	}
+
+	// 1. 还原Merkle根
+	root, matches, err := mb.Tree.ExtractMatches()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(root, mb.Header.MerkleRoot) {
+		return errors.New("merkle root mismatch")
+	}
+
+	// 2. 交易必须与证明中的匹配项一一对应
+	if len(matches) != len(mb.Transactions) {
+		return errors.New("matched transaction count mismatch")
+	}
+	for i, tx := range mb.Transactions {
+		if tx == nil || !bytes.Equal(tx.ID(), matches[i]) {
+			return errors.New("matched transaction not in merkle proof")
+		}
+	}
+
+	return nil
+}
+
+// 编码过滤器
+func EncodeBloomFilter(filter *BloomFilter) []byte {
+	filter.lock.RLock()
+	defer filter.lock.RUnlock()
+
+	data, _ := json.Marshal(filter)
+	return data
+}
+
+// 解码过滤器
+func DecodeBloomFilter(data []byte) (*BloomFilter, error) {
+
+	var filter BloomFilter
+	if err := json.Unmarshal(data, &filter); err != nil {
+		return nil, err
+	}
+	if !filter.IsValid() {
+		return nil, errors.New("invalid bloom filter")
+	}
+
+	return &filter, nil
+}
+
+// 解码MerkleBlock
+func DecodeMerkleBlock(data []byte) (*MerkleBlock, error) {
+
+	var mb MerkleBlock
+	if err := json.Unmarshal(data, &mb); err != nil {
+		return nil, err
+	}
+	if mb.Header == nil || mb.Tree == nil {
+		return nil, errors.New("incomplete merkle block")
+	}
+	for _, tx := range mb.Transactions {
+		if tx == nil {
+			return nil, errors.New("merkle block with empty transaction")
+		}
+	}
+
+	return &mb, nil
+}
+
+// 解码新区块通告
+func DecodeBlockInv(data []byte) (*BlockInv, error) {
+
+	var inv BlockInv
+	err := json.Unmarshal(data, &inv)
+
+	return &inv, err
+}
+
+// 解码过滤区块请求
+func DecodeGetMerkleBlock(data []byte) (*GetMerkleBlock, error) {
+
+	var req GetMerkleBlock
+	err := json.Unmarshal(data, &req)
+
+	return &req, err
+}
+
+// murmurHash3 32位MurmurHash3,BIP37使用的hash函数
+func murmurHash3(seed uint32, data []byte) uint32 {
+
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+	)
+
+	h := seed
+	n := len(data) / 4
+
+	// 1. 按4字节处理
+	for i := 0; i < n; i++ {
+		k := binary.LittleEndian.Uint32(data[i*4:])
+		k *= c1
+		k = (k << 15) | (k >> 17)
+		k *= c2
+
+		h ^= k
+		h = (h << 13) | (h >> 19)
+		h = h*5 + 0xe6546b64
+	}
+
+	// 2. 处理剩余字节
+	tail := data[n*4:]
+	var k uint32
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = (k << 15) | (k >> 17)
+		k *= c2
+		h ^= k
+	}
+
+	// 3. 混淆
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+
+	return h
+}
@@ -0,0 +1,151 @@
+package p2p
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	blockchain "github.com/Alan-333333/simple-blockchain/block/chain"
+	"github.com/Alan-333333/simple-blockchain/transaction"
+	"github.com/Alan-333333/simple-blockchain/wallet"
+)
+
+func TestBloomFilter(t *testing.T) {
+
+	filter := NewBloomFilter(10, 0.0001, 0)
+	filter.AddAddress("alice")
+
+	if !filter.Contains([]byte("alice")) {
+		t.Error("filter should contain added address")
+	}
+
+	if filter.Contains([]byte("bob")) {
+		t.Error("filter should not contain other address")
+	}
+
+	// 编解码后仍然匹配
+	decoded, err := DecodeBloomFilter(EncodeBloomFilter(filter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Contains([]byte("alice")) {
+		t.Error("decoded filter should contain added address")
+	}
+}
+
+func TestMerkleBlock(t *testing.T) {
+
+	// 1. 构造包含多笔交易的区块
+	walletA := wallet.NewWallet()
+	block := &blockchain.Block{}
+	for i := 0; i < 5; i++ {
+		recipient := wallet.NewWallet()
+		tx := transaction.NewTransaction(walletA.GetAddress(), recipient.GetAddress(), 10, 0, uint64(i))
+		tx.Sign(walletA.PrivateKey)
+		block.Transactions = append(block.Transactions, tx)
+	}
+	block.MerkleRoot = blockchain.CalcMerkleRoot(block.Transactions)
+
+	// 2. 只匹配其中一笔交易
+	target := block.Transactions[3]
+	filter := NewBloomFilter(1, 0.0001, 0)
+	filter.AddAddress(target.Recipient)
+
+	merkleBlock := NewMerkleBlock(block, filter)
+	if err := merkleBlock.Verify(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(merkleBlock.Transactions) != 1 || merkleBlock.Transactions[0] != target {
+		t.Errorf("expected only the matching transaction, got %d", len(merkleBlock.Transactions))
+	}
+
+	// 3. 篡改交易后校验失败
+	merkleBlock.Transactions[0] = block.Transactions[0]
+	if err := merkleBlock.Verify(); err == nil {
+		t.Error("tampered merkle block should not verify")
+	}
+}
+
+func TestMalformedMerkleBlock(t *testing.T) {
+
+	// 1. 只有一笔交易的区块,证明与根一致但交易为null
+	walletA := wallet.NewWallet()
+	tx := transaction.NewTransaction(walletA.GetAddress(), wallet.NewWallet().GetAddress(), 10, 0, 0)
+	tx.Sign(walletA.PrivateKey)
+	block := &blockchain.Block{Hash: []byte("block hash"), Transactions: []*transaction.Transaction{tx}}
+	block.MerkleRoot = blockchain.CalcMerkleRoot(block.Transactions)
+
+	filter := NewBloomFilter(1, 0.0001, 0)
+	filter.AddAddress(tx.Sender)
+	var fields map[string]interface{}
+	data, _ := json.Marshal(NewMerkleBlock(block, filter))
+	json.Unmarshal(data, &fields)
+	fields["Transactions"] = []interface{}{nil}
+	malformed, _ := json.Marshal(fields)
+
+	if _, err := DecodeMerkleBlock(malformed); err == nil {
+		t.Errorf("merkle block with null transaction decoded")
+	}
+	if _, err := DecodeMerkleBlock([]byte(`{"Transactions":[]}`)); err == nil {
+		t.Errorf("merkle block without header decoded")
+	}
+
+	// 2. 未请求和请求过的peer发送的错误区块都不会导致panic
+	s := NewServer(0)
+	peer := &Peer{ID: "peer"}
+	s.handleMessage(&Message{MsgType: MsgTypeMerkleBlock, Data: malformed}, peer)
+	peer.setFilterSent(true)
+	peer.requestMerkleBlock(block.Hash, time.Now())
+	s.handleMessage(&Message{MsgType: MsgTypeMerkleBlock, Data: malformed}, peer)
+}
+
+func TestMerkleBlockRequests(t *testing.T) {
+
+	peer := &Peer{ID: "peer"}
+	now := time.Now()
+
+	// 1. 未加载过滤器的peer不能请求,也不接受其过滤区块
+	if peer.requestMerkleBlock([]byte("a"), now) {
+		t.Errorf("requested merkle block without a filter")
+	}
+	if peer.takeMerkleRequest([]byte("a"), now) {
+		t.Errorf("accepted merkle block from peer without a filter")
+	}
+
+	// 2. 只接受请求过的区块,每个请求只能使用一次
+	peer.setFilterSent(true)
+	if !peer.requestMerkleBlock([]byte("a"), now) {
+		t.Fatalf("merkle block request rejected")
+	}
+	if peer.takeMerkleRequest([]byte("b"), now) {
+		t.Errorf("accepted unrequested merkle block")
+	}
+	if !peer.takeMerkleRequest([]byte("a"), now) {
+		t.Errorf("requested merkle block rejected")
+	}
+	if peer.takeMerkleRequest([]byte("a"), now) {
+		t.Errorf("merkle block request used twice")
+	}
+
+	// 3. 请求数量有上限,超时的请求被丢弃
+	for i := 0; i < MAX_MERKLE_BLOCK_REQUESTS; i++ {
+		peer.requestMerkleBlock([]byte(fmt.Sprintf("block %d", i)), now)
+	}
+	if peer.requestMerkleBlock([]byte("c"), now) {
+		t.Errorf("merkle block requests exceeded limit")
+	}
+	if !peer.requestMerkleBlock([]byte("c"), now.Add(MERKLE_BLOCK_TIMEOUT+time.Second)) {
+		t.Errorf("expired merkle block requests not dropped")
+	}
+	if peer.takeMerkleRequest([]byte("block 0"), now.Add(MERKLE_BLOCK_TIMEOUT+time.Second)) {
+		t.Errorf("accepted expired merkle block request")
+	}
+
+	// 4. 清除过滤器后丢弃未完成的请求
+	peer.setFilterSent(false)
+	if peer.takeMerkleRequest([]byte("c"), now) {
+		t.Errorf("accepted merkle block after clearing the filter")
+	}
+}
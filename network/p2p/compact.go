@@ -0,0 +1,288 @@
+package p2p
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	blockchain "github.com/Alan-333333/simple-blockchain/block/chain"
+	"github.com/Alan-333333/simple-blockchain/transaction"
+)
+
+// 短交易ID的字节数
+const ShortIDLen = 6
+
+// 等待补全交易的紧凑区块的数量上限,超出时丢弃最早的区块
+const MAX_PENDING_COMPACT_BLOCKS = 64
+
+// 每个peer等待补全的紧凑区块的数量上限
+const MAX_PENDING_COMPACT_BLOCKS_PER_PEER = 4
+
+// 等待缺失交易的超时时间,超时的区块被丢弃
+const COMPACT_BLOCK_TIMEOUT = 30 * time.Second
+
+// CompactBlock 紧凑区块,只包含区块头和短交易ID
+type CompactBlock struct {
+	Header *blockchain.Block
+
+	// 计算短交易ID的随机数,防止构造碰撞
+	Nonce uint64
+
+	ShortIDs [][]byte
+}
+
+// GetBlockTxn 请求紧凑区块中缺失的交易
+type GetBlockTxn struct {
+	BlockHash []byte
+	Indexes   []int
+}
+
+// BlockTxn 回复缺失的交易
+type BlockTxn struct {
+	BlockHash    []byte
+	Transactions []*transaction.Transaction
+}
+
+// 等待补全交易的紧凑区块
+type pendingBlock struct {
+	block   *blockchain.Block
+	missing []int
+	peer    string    // 发送紧凑区块的peer,只接受该peer回复的交易
+	added   time.Time // 请求缺失交易的时间
+
+	// 紧凑区块的随机数和短ID,补全的交易必须与缺失位置的短ID一致
+	nonce    uint64
+	shortIDs [][]byte
+}
+
+// 正在重建的紧凑区块,按区块hash索引
+type compactBlocks struct {
+	pending map[string]*pendingBlock
+	byPeer  map[string]int // 每个peer等待补全的区块数量
+	lock    sync.Mutex
+}
+
+func newCompactBlocks() *compactBlocks {
+	return &compactBlocks{
+		pending: make(map[string]*pendingBlock),
+		byPeer:  make(map[string]int),
+	}
+}
+
+// NewCompactBlock 从完整区块构造紧凑区块
+func NewCompactBlock(block *blockchain.Block) *CompactBlock {
+
+	nonceBytes := make([]byte, 8)
+	rand.Read(nonceBytes)
+
+	cb := &CompactBlock{
+		Header:   block.Header(),
+		Nonce:    binary.LittleEndian.Uint64(nonceBytes),
+		ShortIDs: make([][]byte, len(block.Transactions)),
+	}
+
+	for i, tx := range block.Transactions {
+		cb.ShortIDs[i] = cb.ShortID(tx.ID())
+	}
+
+	return cb
+}
+
+// ShortID 计算交易的短ID,由区块hash和随机数加盐
+func (cb *CompactBlock) ShortID(txid []byte) []byte {
+
+	nonceBytes := make([]byte, 8)
+	binary.LittleEndian.PutUint64(nonceBytes, cb.Nonce)
+
+	data := bytes.Join([][]byte{cb.Header.Hash, nonceBytes, txid}, []byte{})
+	hash := sha256.Sum256(data)
+
+	return hash[:ShortIDLen]
+}
+
+// Reconstruct 使用交易池中的交易重建区块,返回区块和缺失交易的下标
+func (cb *CompactBlock) Reconstruct(pool []*transaction.Transaction) (*blockchain.Block, []int) {
+
+	// 1. 建立短ID到交易池交易的索引,碰撞的短ID视为缺失
+	candidates := make(map[string]*transaction.Transaction, len(pool))
+	collided := make(map[string]bool)
+	for _, tx := range pool {
+		id := string(cb.ShortID(tx.ID()))
+		if _, ok := candidates[id]; ok {
+			collided[id] = true
+			continue
+		}
+		candidates[id] = tx
+	}
+
+	// 2. 按顺序填充交易
+	block := cb.Header.Header()
+	block.Transactions = make([]*transaction.Transaction, len(cb.ShortIDs))
+	missing := []int{}
+	for i, shortID := range cb.ShortIDs {
+		tx, ok := candidates[string(shortID)]
+		if !ok || collided[string(shortID)] {
+			missing = append(missing, i)
+			continue
+		}
+		block.Transactions[i] = tx
+	}
+
+	return block, missing
+}
+
+// 保存等待补全的区块,peer等待的区块过多时返回false,不再请求缺失的交易
+// 总数达到上限时丢弃最早的区块
+func (c *compactBlocks) add(cb *CompactBlock, block *blockchain.Block, missing []int, peer string, now time.Time) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// 1. 丢弃超时的区块
+	c.expire(now)
+
+	// 2. 已在等待的区块不重复请求
+	hash := string(block.Hash)
+	if _, ok := c.pending[hash]; ok {
+		return false
+	}
+	if c.byPeer[peer] >= MAX_PENDING_COMPACT_BLOCKS_PER_PEER {
+		return false
+	}
+
+	// 3. 总数达到上限时丢弃最早的区块
+	if len(c.pending) >= MAX_PENDING_COMPACT_BLOCKS {
+		var oldest string
+		for h, p := range c.pending {
+			if oldest == "" || p.added.Before(c.pending[oldest].added) {
+				oldest = h
+			}
+		}
+		c.remove(oldest)
+	}
+
+	c.pending[hash] = &pendingBlock{
+		block:    block,
+		missing:  missing,
+		peer:     peer,
+		added:    now,
+		nonce:    cb.Nonce,
+		shortIDs: cb.ShortIDs,
+	}
+	c.byPeer[peer]++
+
+	return true
+}
+
+// 使用收到的交易补全区块,补全后从等待列表中移除
+// 交易为空或与短ID不一致时丢弃该区块并返回错误
+func (c *compactBlocks) fill(blockTxn *BlockTxn, peer string, now time.Time) (*blockchain.Block, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.expire(now)
+
+	pending, ok := c.pending[string(blockTxn.BlockHash)]
+	if !ok || pending.peer != peer {
+		return nil, errors.New("unknown compact block")
+	}
+	if len(blockTxn.Transactions) != len(pending.missing) {
+		return nil, errors.New("block transaction count mismatch")
+	}
+
+	c.remove(string(blockTxn.BlockHash))
+
+	// 校验每笔交易的短ID,全部通过后再填充
+	cb := &CompactBlock{Header: pending.block, Nonce: pending.nonce}
+	for i, idx := range pending.missing {
+		tx := blockTxn.Transactions[i]
+		if tx == nil {
+			return nil, errors.New("empty block transaction")
+		}
+		if !bytes.Equal(cb.ShortID(tx.ID()), pending.shortIDs[idx]) {
+			return nil, errors.New("block transaction does not match short id")
+		}
+	}
+	for i, idx := range pending.missing {
+		pending.block.Transactions[idx] = blockTxn.Transactions[i]
+	}
+
+	return pending.block, nil
+}
+
+// 丢弃peer等待补全的所有区块,peer断开时调用
+func (c *compactBlocks) removePeer(peer string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for hash, pending := range c.pending {
+		if pending.peer == peer {
+			c.remove(hash)
+		}
+	}
+}
+
+// 丢弃超时的区块,调用方持有锁
+func (c *compactBlocks) expire(now time.Time) {
+	for hash, pending := range c.pending {
+		if now.Sub(pending.added) > COMPACT_BLOCK_TIMEOUT {
+			c.remove(hash)
+		}
+	}
+}
+
+// 移除等待的区块,调用方持有锁
+func (c *compactBlocks) remove(hash string) {
+	pending, ok := c.pending[hash]
+	if !ok {
+		return
+	}
+	delete(c.pending, hash)
+
+	c.byPeer[pending.peer]--
+	if c.byPeer[pending.peer] == 0 {
+		delete(c.byPeer, pending.peer)
+	}
+}
+
+// 编码紧凑区块
+func EncodeCompactBlock(cb *CompactBlock) []byte {
+	data, _ := json.Marshal(cb)
+	return data
+}
+
+// 解码紧凑区块
+func DecodeCompactBlock(data []byte) (*CompactBlock, error) {
+
+	var cb CompactBlock
+	if err := json.Unmarshal(data, &cb); err != nil {
+		return nil, err
+	}
+	if cb.Header == nil {
+		return nil, errors.New("compact block without header")
+	}
+
+	return &cb, nil
+}
+
+// 解码缺失交易请求
+func DecodeGetBlockTxn(data []byte) (*GetBlockTxn, error) {
+
+	var req GetBlockTxn
+	err := json.Unmarshal(data, &req)
+
+	return &req, err
+}
+
+// 解码缺失交易回复
+func DecodeBlockTxn(data []byte) (*BlockTxn, error) {
+
+	var resp BlockTxn
+	err := json.Unmarshal(data, &resp)
+
+	return &resp, err
+}
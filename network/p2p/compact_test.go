@@ -0,0 +1,157 @@
+package p2p
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	blockchain "github.com/Alan-333333/simple-blockchain/block/chain"
+	"github.com/Alan-333333/simple-blockchain/transaction"
+	"github.com/Alan-333333/simple-blockchain/wallet"
+)
+
+func TestCompactBlockReconstruct(t *testing.T) {
+
+	// 1. 构造包含3笔交易的区块
+	walletA := wallet.NewWallet()
+	walletB := wallet.NewWallet()
+	block := &blockchain.Block{Hash: []byte("block hash")}
+	for i := 0; i < 3; i++ {
+		tx := transaction.NewTransaction(walletA.GetAddress(), walletB.GetAddress(), 10, 0, uint64(i))
+		tx.Sign(walletA.PrivateKey)
+		block.Transactions = append(block.Transactions, tx)
+	}
+
+	// 编解码后重建
+	compactBlock, err := DecodeCompactBlock(EncodeCompactBlock(NewCompactBlock(block)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 2. 交易池只有前两笔交易
+	pool := []*transaction.Transaction{block.Transactions[1], block.Transactions[0]}
+	rebuilt, missing := compactBlock.Reconstruct(pool)
+
+	if len(missing) != 1 || missing[0] != 2 {
+		t.Fatalf("expected transaction 2 to be missing, got %v", missing)
+	}
+
+	// 3. 补全缺失的交易
+	c := newCompactBlocks()
+	c.add(compactBlock, rebuilt, missing, "peer", time.Now())
+	full, err := c.fill(&BlockTxn{BlockHash: block.Hash, Transactions: block.Transactions[2:]}, "peer", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tx := range full.Transactions {
+		if tx != block.Transactions[i] {
+			t.Errorf("transaction %d not rebuilt in order", i)
+		}
+	}
+}
+
+func TestCompactBlocksPendingLimits(t *testing.T) {
+
+	c := newCompactBlocks()
+	now := time.Now()
+	newBlock := func(i int) *blockchain.Block {
+		return &blockchain.Block{Hash: []byte(fmt.Sprintf("block %d", i)), Transactions: make([]*transaction.Transaction, 1)}
+	}
+
+	// 1. 每个peer等待的区块数量有上限
+	for i := 0; i < MAX_PENDING_COMPACT_BLOCKS_PER_PEER; i++ {
+		if !c.add(&CompactBlock{}, newBlock(i), []int{0}, "a", now) {
+			t.Fatalf("block %d rejected", i)
+		}
+	}
+	if c.add(&CompactBlock{}, newBlock(100), []int{0}, "a", now) {
+		t.Errorf("peer exceeded pending block limit")
+	}
+
+	// 只接受请求的peer回复的交易
+	resp := &BlockTxn{BlockHash: []byte("block 0"), Transactions: make([]*transaction.Transaction, 1)}
+	if _, err := c.fill(resp, "b", now); err == nil {
+		t.Errorf("filled block from another peer")
+	}
+
+	// 2. peer断开后丢弃其等待的区块
+	c.removePeer("a")
+	if len(c.pending) != 0 || len(c.byPeer) != 0 {
+		t.Errorf("pending blocks of disconnected peer not dropped")
+	}
+
+	// 3. 总数达到上限时丢弃最早的区块
+	for i := 0; i < MAX_PENDING_COMPACT_BLOCKS; i++ {
+		c.add(&CompactBlock{}, newBlock(i), []int{0}, fmt.Sprintf("peer %d", i), now.Add(time.Duration(i)*time.Millisecond))
+	}
+	c.add(&CompactBlock{}, newBlock(MAX_PENDING_COMPACT_BLOCKS), []int{0}, "new", now.Add(time.Second))
+	if len(c.pending) != MAX_PENDING_COMPACT_BLOCKS {
+		t.Errorf("pending blocks %d exceed limit", len(c.pending))
+	}
+	if _, ok := c.pending["block 0"]; ok {
+		t.Errorf("oldest pending block not dropped")
+	}
+
+	// 4. 超时的区块被丢弃
+	c.expire(now.Add(time.Second + COMPACT_BLOCK_TIMEOUT + time.Millisecond))
+	if len(c.pending) != 0 || len(c.byPeer) != 0 {
+		t.Errorf("expired pending blocks not dropped, %d left", len(c.pending))
+	}
+}
+
+func TestCompactBlockFillMismatch(t *testing.T) {
+
+	walletA := wallet.NewWallet()
+	block := &blockchain.Block{Hash: []byte("block hash")}
+	for i := 0; i < 2; i++ {
+		tx := transaction.NewTransaction(walletA.GetAddress(), wallet.NewWallet().GetAddress(), 10, 0, uint64(i))
+		tx.Sign(walletA.PrivateKey)
+		block.Transactions = append(block.Transactions, tx)
+	}
+	compactBlock := NewCompactBlock(block)
+
+	// 交易池为空,两笔交易都缺失
+	c := newCompactBlocks()
+	fill := func(txs []*transaction.Transaction) error {
+		rebuilt, missing := compactBlock.Reconstruct(nil)
+		c.add(compactBlock, rebuilt, missing, "peer", time.Now())
+		_, err := c.fill(&BlockTxn{BlockHash: block.Hash, Transactions: txs}, "peer", time.Now())
+		return err
+	}
+
+	// 1. 空交易
+	if err := fill([]*transaction.Transaction{block.Transactions[0], nil}); err == nil {
+		t.Errorf("filled block with null transaction")
+	}
+
+	// 2. 与短ID不一致的交易,顺序错误也不接受
+	other := transaction.NewTransaction(walletA.GetAddress(), walletA.GetAddress(), 1, 0, 9)
+	if err := fill([]*transaction.Transaction{block.Transactions[0], other}); err == nil {
+		t.Errorf("filled block with unexpected transaction")
+	}
+	if err := fill([]*transaction.Transaction{block.Transactions[1], block.Transactions[0]}); err == nil {
+		t.Errorf("filled block with transactions out of order")
+	}
+
+	// 失败后区块不再等待
+	if len(c.pending) != 0 {
+		t.Errorf("rejected block still pending")
+	}
+
+	// 3. 正确的交易
+	if err := fill(block.Transactions); err != nil {
+		t.Errorf("valid block transactions rejected: %v", err)
+	}
+
+	// 4. 解码null交易后同样被拒绝
+	resp, err := DecodeBlockTxn([]byte(`{"BlockHash":"YmxvY2sgaGFzaA==","Transactions":[null,null]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rebuilt, missing := compactBlock.Reconstruct(nil)
+	c.add(compactBlock, rebuilt, missing, "peer", time.Now())
+	if _, err := c.fill(resp, "peer", time.Now()); err == nil {
+		t.Errorf("filled block from null block txn")
+	}
+}
@@ -3,10 +3,12 @@ package p2p
 import (
 	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net"
 	"strconv"
+	"time"
 
 	blockchain "github.com/Alan-333333/simple-blockchain/block/chain"
 	"github.com/Alan-333333/simple-blockchain/transaction"
@@ -86,19 +88,77 @@ func (node *Node) BroadcastTx(tx *transaction.Transaction) {
 	// 构造消息体
 	data, _ := json.Marshal(tx)
 	// 广播消息
-	node.Server.Broadcast(MsgTypeTx, data, nil)
+	node.Server.RelayTx(tx, data, nil)
 }
 
 // 广博区块信息到网络
 func (node *Node) BroadcastBlock(block *blockchain.Block) {
 
-	data, _ := json.Marshal(block)
-	node.Server.Broadcast(MsgTypeBlock, data, nil)
+	node.Server.RelayBlock(block, nil)
 }
 
-// 广博钱包信息到网络
-func (node *Node) BroadcastWallet(w *wallet.Wallet) {
+// 使用本节点的统计数据估算手续费
+func (node *Node) EstimateFee(target int, confidence float64) (*transaction.FeeEstimate, error) {
 
-	encodeWallet, _ := wallet.EncodedWallet(w)
-	node.Server.Broadcast(MsgTypeWallet, encodeWallet, nil)
+	estimator := transaction.GetFeeEstimator()
+	if estimator == nil {
+		return nil, errors.New("fee estimator not started")
+	}
+	return estimator.EstimateFee(target, confidence)
+}
+
+// 向所有peer请求手续费估算
+func (node *Node) RequestFeeEstimate(target int, confidence float64) {
+
+	data, _ := json.Marshal(&GetFeeEstimate{Target: target, Confidence: confidence})
+	node.Server.Broadcast(MsgTypeGetFeeEstimate, data, nil)
+}
+
+// 向所有peer加载布隆过滤器,并请求匹配的交易池交易
+func (node *Node) LoadFilter(filter *BloomFilter) {
+
+	for _, peer := range node.Server.GetPeers() {
+		peer.setFilterSent(true)
+	}
+	node.Server.Broadcast(MsgTypeFilterLoad, EncodeBloomFilter(filter), nil)
+	node.Server.Broadcast(MsgTypeMempool, []byte{}, nil)
+}
+
+// 清除所有peer上的布隆过滤器
+func (node *Node) ClearFilter() {
+
+	for _, peer := range node.Server.GetPeers() {
+		peer.setFilterSent(false)
+	}
+	node.Server.Broadcast(MsgTypeFilterClear, []byte{}, nil)
+}
+
+// 向一个加载了过滤器的peer请求过滤后的区块
+func (node *Node) RequestMerkleBlock(hash []byte) error {
+
+	req, _ := json.Marshal(&GetMerkleBlock{BlockHash: hash})
+	for _, peer := range node.Server.GetPeers() {
+		if peer.requestMerkleBlock(hash, time.Now()) {
+			peer.Send(EncodeMessage(MsgTypeGetMerkleBlock, req))
+			return nil
+		}
+	}
+
+	return errors.New("no filtered peer to request merkle block from")
+}
+
+// 广播签名的地址公告,钱包私钥和余额只保存在本地
+func (node *Node) AnnounceWallet(w *wallet.Wallet) error {
+
+	announcement, err := wallet.NewAnnouncement(w)
+	if err != nil {
+		return err
+	}
+	data, err := wallet.EncodeAnnouncement(announcement)
+	if err != nil {
+		return err
+	}
+
+	node.Server.Broadcast(MsgTypeAddrAnnounce, data, nil)
+	return nil
 }
@@ -3,11 +3,18 @@ package p2p
 import (
 	"io"
 	"net"
+	"sync"
 	"time"
 )
 
 const VERSION = 1
 
+// 每个peer转发地址公告的速率限制: 每分钟的数量和允许的突发数量
+const (
+	ANNOUNCE_RATE_PER_MINUTE = 30
+	ANNOUNCE_BURST           = 10
+)
+
 type Peer struct {
 	ID   string
 	Conn net.Conn
@@ -19,6 +26,24 @@ type Peer struct {
 
 	// 关闭标志
 	closed chan bool
+
+	// 对端断开连接时关闭
+	disconnected chan struct{}
+
+	// 对端加载的布隆过滤器,为nil时不过滤
+	filter     *BloomFilter
+	filterLock sync.RWMutex
+
+	// 是否向对端加载了布隆过滤器,以及向对端请求的过滤区块
+	// 只接受这样的peer回复的、已请求的过滤区块
+	filterSent     bool
+	merkleRequests map[string]time.Time
+	merkleLock     sync.Mutex
+
+	// 对端地址公告的令牌桶
+	announceTokens float64
+	announceLast   time.Time
+	announceLock   sync.Mutex
 }
 
 // 创建一个新的Peer
@@ -29,6 +54,8 @@ func NewPeer(conn net.Conn) *Peer {
 		sendQueue: make(chan []byte),
 		msgChan:   make(chan *Message),
 		closed:    make(chan bool),
+
+		disconnected: make(chan struct{}),
 	}
 }
 
@@ -47,6 +74,7 @@ func (p *Peer) ReadLoop() {
 		if err != nil {
 			if err == io.EOF {
 				// 对端关闭
+				close(p.disconnected)
 				p.Close()
 				return
 			}
@@ -89,6 +117,30 @@ func (p *Peer) Close() {
 	p.closed <- true
 }
 
+// AllowAnnouncement 按速率限制决定是否处理对端的地址公告
+// 令牌以每分钟ANNOUNCE_RATE_PER_MINUTE个的速度恢复,最多ANNOUNCE_BURST个
+func (p *Peer) AllowAnnouncement(now time.Time) bool {
+	p.announceLock.Lock()
+	defer p.announceLock.Unlock()
+
+	if p.announceLast.IsZero() {
+		p.announceTokens = ANNOUNCE_BURST
+	} else {
+		p.announceTokens += now.Sub(p.announceLast).Minutes() * ANNOUNCE_RATE_PER_MINUTE
+		if p.announceTokens > ANNOUNCE_BURST {
+			p.announceTokens = ANNOUNCE_BURST
+		}
+	}
+	p.announceLast = now
+
+	if p.announceTokens < 1 {
+		return false
+	}
+	p.announceTokens--
+
+	return true
+}
+
 // 检查关闭状态
 func isClosed(ch chan bool) bool {
 	select {
@@ -99,6 +151,92 @@ func isClosed(ch chan bool) bool {
 	}
 }
 
+// SetFilter 设置对端的布隆过滤器
+func (p *Peer) SetFilter(filter *BloomFilter) {
+	p.filterLock.Lock()
+	defer p.filterLock.Unlock()
+
+	p.filter = filter
+}
+
+// Filter 获取对端的布隆过滤器
+func (p *Peer) Filter() *BloomFilter {
+	p.filterLock.RLock()
+	defer p.filterLock.RUnlock()
+
+	return p.filter
+}
+
+// 记录是否向对端加载了布隆过滤器,清除过滤器时丢弃未完成的请求
+func (p *Peer) setFilterSent(sent bool) {
+	p.merkleLock.Lock()
+	defer p.merkleLock.Unlock()
+
+	p.filterSent = sent
+	if !sent {
+		p.merkleRequests = nil
+	}
+}
+
+// FilterSent 是否向对端加载了布隆过滤器
+func (p *Peer) FilterSent() bool {
+	p.merkleLock.Lock()
+	defer p.merkleLock.Unlock()
+
+	return p.filterSent
+}
+
+// 记录向对端请求的过滤区块,未加载过滤器、已请求或请求过多时返回false
+func (p *Peer) requestMerkleBlock(hash []byte, now time.Time) bool {
+	p.merkleLock.Lock()
+	defer p.merkleLock.Unlock()
+
+	if !p.filterSent {
+		return false
+	}
+	p.expireMerkleRequests(now)
+
+	if _, ok := p.merkleRequests[string(hash)]; ok {
+		return false
+	}
+	if len(p.merkleRequests) >= MAX_MERKLE_BLOCK_REQUESTS {
+		return false
+	}
+	if p.merkleRequests == nil {
+		p.merkleRequests = make(map[string]time.Time)
+	}
+	p.merkleRequests[string(hash)] = now
+
+	return true
+}
+
+// 检查收到的过滤区块是否已向对端请求,请求只能使用一次
+func (p *Peer) takeMerkleRequest(hash []byte, now time.Time) bool {
+	p.merkleLock.Lock()
+	defer p.merkleLock.Unlock()
+
+	if !p.filterSent {
+		return false
+	}
+	p.expireMerkleRequests(now)
+
+	if _, ok := p.merkleRequests[string(hash)]; !ok {
+		return false
+	}
+	delete(p.merkleRequests, string(hash))
+
+	return true
+}
+
+// 丢弃超时的过滤区块请求,调用方持有锁
+func (p *Peer) expireMerkleRequests(now time.Time) {
+	for hash, requested := range p.merkleRequests {
+		if now.Sub(requested) > MERKLE_BLOCK_TIMEOUT {
+			delete(p.merkleRequests, hash)
+		}
+	}
+}
+
 func (p *Peer) SendPing() {
 	data := []byte("ping")
 	p.sendQueue <- EncodeMessage(MsgTypePing, data)
@@ -0,0 +1,43 @@
+package p2p
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllowAnnouncement(t *testing.T) {
+
+	peer := &Peer{}
+	now := time.Now()
+
+	// 1. 允许突发数量的公告,之后被限制
+	for i := 0; i < ANNOUNCE_BURST; i++ {
+		if !peer.AllowAnnouncement(now) {
+			t.Fatalf("announcement %d rejected within burst", i)
+		}
+	}
+	if peer.AllowAnnouncement(now) {
+		t.Errorf("announcement allowed beyond burst")
+	}
+
+	// 2. 令牌随时间恢复
+	now = now.Add(time.Minute / ANNOUNCE_RATE_PER_MINUTE)
+	if !peer.AllowAnnouncement(now) {
+		t.Errorf("announcement rejected after refill")
+	}
+	if peer.AllowAnnouncement(now) {
+		t.Errorf("refill exceeded rate")
+	}
+
+	// 3. 长时间空闲后不超过突发数量
+	now = now.Add(time.Hour)
+	allowed := 0
+	for i := 0; i < 2*ANNOUNCE_BURST; i++ {
+		if peer.AllowAnnouncement(now) {
+			allowed++
+		}
+	}
+	if allowed != ANNOUNCE_BURST {
+		t.Errorf("allowed %d after idle, want %d", allowed, ANNOUNCE_BURST)
+	}
+}
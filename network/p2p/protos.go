@@ -15,8 +15,33 @@ const (
 	MsgTypeVersion = 1
 	MsgTypeTx      = 2
 	MsgTypeBlock   = 3
-	MsgTypeWallet  = 4
-	MsgTypePing    = 999
+
+	// 4 曾用于广播包含私钥的钱包,已废弃,收到时直接忽略
+
+	// 布隆过滤器相关消息(BIP37)
+	MsgTypeFilterLoad  = 5
+	MsgTypeFilterAdd   = 6
+	MsgTypeFilterClear = 7
+	MsgTypeMerkleBlock = 8
+	MsgTypeMempool     = 9
+
+	// 紧凑区块相关消息
+	MsgTypeCompactBlock = 10
+	MsgTypeGetBlockTxn  = 11
+	MsgTypeBlockTxn     = 12
+
+	// 手续费估算相关消息
+	MsgTypeGetFeeEstimate = 13
+	MsgTypeFeeEstimate    = 14
+
+	// 签名的地址公告,只包含公钥
+	MsgTypeAddrAnnounce = 15
+
+	// 向加载了过滤器的peer通告新区块,对端按需请求过滤后的区块
+	MsgTypeBlockInv       = 16
+	MsgTypeGetMerkleBlock = 17
+
+	MsgTypePing = 999
 )
 
 // 版本消息
@@ -114,6 +139,30 @@ func DecodeMessage(data []byte) *Message {
 	return msg
 }
 
+// 手续费估算请求
+type GetFeeEstimate struct {
+	Target     int
+	Confidence float64
+}
+
+// 解码手续费估算请求
+func DecodeGetFeeEstimate(data []byte) (*GetFeeEstimate, error) {
+
+	var req GetFeeEstimate
+	err := json.Unmarshal(data, &req)
+
+	return &req, err
+}
+
+// 解码手续费估算结果
+func DecodeFeeEstimate(data []byte) (*transaction.FeeEstimate, error) {
+
+	var estimate transaction.FeeEstimate
+	err := json.Unmarshal(data, &estimate)
+
+	return &estimate, err
+}
+
 // 解码Transaction
 func DecodeTransaction(data []byte) (*transaction.Transaction, error) {
 
@@ -1,6 +1,8 @@
 package p2p
 
 import (
+	"bytes"
+	"encoding/json"
 	"testing"
 
 	"github.com/Alan-333333/simple-blockchain/transaction"
@@ -38,17 +40,19 @@ func TestDecodeTransaction(t *testing.T) {
 	walletB := wallet.NewWallet()
 
 	// 2. 构造交易
-	tx := transaction.NewTransaction(walletA.GetAddress(), walletB.GetAddress(), 10)
+	tx := transaction.NewTransaction(walletA.GetAddress(), walletB.GetAddress(), 10, 0, 0)
 
 	// 3. 签名
 	tx.Sign(walletA.PrivateKey)
 	// 4. 广播交易
 
 	// 编码
-	data := EncodeMessage(MsgTypeTx, tx)
+	txData, _ := json.Marshal(tx)
+	data := EncodeMessage(MsgTypeTx, txData)
 
 	// 调用解码
-	decoded, err := DecodeTransaction(data)
+	msg := DecodeMessage(data)
+	decoded, err := DecodeTransaction(msg.Data)
 
 	// 检查错误
 	if err != nil {
@@ -65,5 +69,8 @@ func TestDecodeTransaction(t *testing.T) {
 	}
 
 	// 校验其他字段
+	if !bytes.Equal(decoded.ID(), tx.ID()) {
+		t.Errorf("Decoded ID mismatch")
+	}
 
 }
@@ -29,14 +29,16 @@ func main() {
 	walletA := wallet.NewWallet()
 	walletB := wallet.NewWallet()
 
+	walletA.Encrypt("passphrase-a")
+	walletB.Encrypt("passphrase-b")
 	walletA.Save()
 	walletB.Save()
 
-	node.BroadcastWallet(walletA)
-	node.BroadcastWallet(walletB)
+	node.AnnounceWallet(walletA)
+	node.AnnounceWallet(walletB)
 
 	// 2. 构造交易
-	tx := transaction.NewTransaction(walletA.GetAddress(), walletB.GetAddress(), 10)
+	tx := transaction.NewTransaction(walletA.GetAddress(), walletB.GetAddress(), 10, 0, 0)
 
 	// 3. 签名
 	tx.Sign(walletA.PrivateKey)
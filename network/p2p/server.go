@@ -1,6 +1,7 @@
 package p2p
 
 import (
+	"encoding/json"
 	"fmt"
 	"net"
 	"sync"
@@ -18,12 +19,16 @@ type Server struct {
 
 	Peers    map[string]*Peer
 	peerLock sync.Mutex
+
+	// 正在重建的紧凑区块
+	compact *compactBlocks
 }
 
 func NewServer(port int) *Server {
 	return &Server{
-		port:  port,
-		Peers: make(map[string]*Peer),
+		port:    port,
+		Peers:   make(map[string]*Peer),
+		compact: newCompactBlocks(),
 	}
 }
 
@@ -75,6 +80,16 @@ func (s *Server) AddPeer(peer *Peer) {
 	s.Peers[peer.ID] = peer
 }
 
+// RemovePeer 移除断开的peer,丢弃该peer等待补全的紧凑区块
+func (s *Server) RemovePeer(peer *Peer) {
+
+	s.peerLock.Lock()
+	delete(s.Peers, peer.ID)
+	s.peerLock.Unlock()
+
+	s.compact.removePeer(peer.ID)
+}
+
 func (s *Server) GetPeers() map[string]*Peer {
 	s.peerLock.Lock()
 	defer s.peerLock.Unlock()
@@ -99,10 +114,78 @@ func (s *Server) Broadcast(msgType int, data interface{}, readPeer *Peer) {
 	}
 }
 
+// RelayTx 转发交易,加载了过滤器的peer只接收匹配的交易
+func (s *Server) RelayTx(tx *transaction.Transaction, data []byte, readPeer *Peer) {
+	peers := s.GetPeers()
+	for _, peer := range peers {
+		if readPeer != nil && readPeer.ID == peer.ID {
+			continue
+		}
+
+		filter := peer.Filter()
+		if filter != nil && !filter.MatchTx(tx) {
+			continue
+		}
+
+		peer.Send(EncodeMessage(MsgTypeTx, data))
+	}
+}
+
+// RelayBlock 以紧凑区块转发区块,加载了过滤器的peer只接收区块通告,按需请求过滤后的区块
+func (s *Server) RelayBlock(block *blockchain.Block, readPeer *Peer) {
+	compactBlock := EncodeCompactBlock(NewCompactBlock(block))
+	inv, _ := json.Marshal(&BlockInv{BlockHash: block.Hash})
+
+	peers := s.GetPeers()
+	for _, peer := range peers {
+		if readPeer != nil && readPeer.ID == peer.ID {
+			continue
+		}
+
+		if peer.Filter() == nil {
+			peer.Send(EncodeMessage(MsgTypeCompactBlock, compactBlock))
+			continue
+		}
+
+		peer.Send(EncodeMessage(MsgTypeBlockInv, inv))
+	}
+}
+
+// 校验区块并添加到区块链,成功后转发给其他节点
+func (s *Server) processBlock(block *blockchain.Block, readPeer *Peer) {
+
+	bc := blockchain.GetBlockchain()
+
+	// 校验
+	if !bc.IsValidBlock(block) {
+		return
+	}
+
+	// 添加到区块链
+	err := bc.AddBlock(block)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	bc.Save()
+
+	// 移除交易池中已确认的交易
+	transaction.GetTxPool().RemoveConfirmed(block.Transactions)
+
+	// 广播给其他节点
+	s.RelayBlock(block, readPeer)
+}
+
 func (s *Server) readPeerMsg(peer *Peer) {
 	for {
-		msg := <-peer.msgChan
-		s.handleMessage(msg, peer)
+		select {
+		case msg := <-peer.msgChan:
+			s.handleMessage(msg, peer)
+		case <-peer.disconnected:
+			s.RemovePeer(peer)
+			return
+		}
 	}
 }
 
@@ -115,7 +198,7 @@ func (s *Server) handleMessage(msg *Message, readPeer *Peer) {
 		// 增加版本校验逻辑
 		version := DecodeVersion(msg.Data)
 		if version.Version != VERSION {
-			delete(s.Peers, readPeer.ID)
+			s.RemovePeer(readPeer)
 		}
 		return
 	case MsgTypeTx:
@@ -124,19 +207,20 @@ func (s *Server) handleMessage(msg *Message, readPeer *Peer) {
 		if err != nil {
 			return
 		}
-		// 校验
-		if !tx.IsValid() {
+		// 校验签名和金额、地址等交易规则
+		if !tx.IsValid() || !blockchain.IsValidTransaction(tx) {
 			return
 		}
 		// 添加到交易池
 		txPool := transaction.GetTxPool()
-		txPool.AddTx(tx)
+		if err := txPool.AddTx(tx); err != nil {
+			return
+		}
 
 		// 广播给其他节点
-		s.Broadcast(MsgTypeTx, msg.Data, readPeer)
+		s.RelayTx(tx, msg.Data, readPeer)
 	case MsgTypeBlock:
 
-		bc := blockchain.GetBlockchain()
 		// 解码
 		block, err := DecodeBlock(msg.Data)
 
@@ -144,29 +228,209 @@ func (s *Server) handleMessage(msg *Message, readPeer *Peer) {
 			fmt.Println(err)
 			return
 		}
-		// 校验
-		if !bc.IsValidBlock(block) {
+
+		s.processBlock(block, readPeer)
+
+	case MsgTypeCompactBlock:
+		// 解码紧凑区块
+		compactBlock, err := DecodeCompactBlock(msg.Data)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		// 已有该区块
+		bc := blockchain.GetBlockchain()
+		if bc.GetBlock(fmt.Sprintf("%x", compactBlock.Header.Hash)) != nil {
 			return
 		}
 
-		// 添加到区块链
-		bc.AddBlock(block)
+		// 使用交易池重建区块
+		txPool := transaction.GetTxPool()
+		block, missing := compactBlock.Reconstruct(txPool.GetTxs())
+		if len(missing) == 0 {
+			s.processBlock(block, readPeer)
+			return
+		}
 
-		bc.Save()
+		// 请求缺失的交易,peer等待补全的区块过多时忽略
+		if !s.compact.add(compactBlock, block, missing, readPeer.ID, time.Now()) {
+			return
+		}
+		req, _ := json.Marshal(&GetBlockTxn{BlockHash: block.Hash, Indexes: missing})
+		readPeer.Send(EncodeMessage(MsgTypeGetBlockTxn, req))
 
-		// 广播给其他节点
-		s.Broadcast(MsgTypeBlock, msg.Data, readPeer)
-	case MsgTypeWallet:
-		wallet, err := wallet.DecodeWallet(msg.Data)
+	case MsgTypeGetBlockTxn:
+		req, err := DecodeGetBlockTxn(msg.Data)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		bc := blockchain.GetBlockchain()
+		block := bc.GetBlock(fmt.Sprintf("%x", req.BlockHash))
+		if block == nil {
+			return
+		}
+
+		// 回复请求的交易
+		resp := &BlockTxn{BlockHash: block.Hash}
+		for _, idx := range req.Indexes {
+			if idx < 0 || idx >= len(block.Transactions) {
+				return
+			}
+			resp.Transactions = append(resp.Transactions, block.Transactions[idx])
+		}
+		data, _ := json.Marshal(resp)
+		readPeer.Send(EncodeMessage(MsgTypeBlockTxn, data))
 
+	case MsgTypeBlockTxn:
+		resp, err := DecodeBlockTxn(msg.Data)
 		if err != nil {
 			fmt.Println(err)
 			return
 		}
 
-		wallet.Save()
+		// 补全紧凑区块
+		block, err := s.compact.fill(resp, readPeer.ID, time.Now())
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		s.processBlock(block, readPeer)
+	case MsgTypeAddrAnnounce:
+		// 超过速率限制的公告直接丢弃
+		if !readPeer.AllowAnnouncement(time.Now()) {
+			return
+		}
+
+		// 校验大小和签名后记录公告,只包含公钥
+		announcement, err := wallet.DecodeAnnouncement(msg.Data)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		// 已知的公告不再转发,避免广播循环
+		if err := announcement.Save(); err != nil {
+			return
+		}
+
+		s.Broadcast(MsgTypeAddrAnnounce, msg.Data, readPeer)
+
+	case MsgTypeFilterLoad:
+		// 加载过滤器
+		filter, err := DecodeBloomFilter(msg.Data)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		readPeer.SetFilter(filter)
+
+	case MsgTypeFilterAdd:
+		// 向已加载的过滤器添加元素
+		filter := readPeer.Filter()
+		if filter == nil || len(msg.Data) > maxFilterAddSize {
+			return
+		}
+		filter.Add(msg.Data)
+
+	case MsgTypeFilterClear:
+		// 清除过滤器,恢复完整转发
+		readPeer.SetFilter(nil)
+
+	case MsgTypeMempool:
+		// 发送交易池中匹配过滤器的交易
+		filter := readPeer.Filter()
+		txPool := transaction.GetTxPool()
+		txPool.Range(func(tx *transaction.Transaction) bool {
+			if filter == nil || filter.MatchTx(tx) {
+				data, _ := json.Marshal(tx)
+				readPeer.Send(EncodeMessage(MsgTypeTx, data))
+			}
+			return true
+		})
+
+	case MsgTypeBlockInv:
+		// 只在向该peer加载了过滤器时请求过滤后的区块
+		inv, err := DecodeBlockInv(msg.Data)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		bc := blockchain.GetBlockchain()
+		if bc.GetBlock(fmt.Sprintf("%x", inv.BlockHash)) != nil {
+			return
+		}
+		if !readPeer.requestMerkleBlock(inv.BlockHash, time.Now()) {
+			return
+		}
+		req, _ := json.Marshal(&GetMerkleBlock{BlockHash: inv.BlockHash})
+		readPeer.Send(EncodeMessage(MsgTypeGetMerkleBlock, req))
+
+	case MsgTypeGetMerkleBlock:
+		// 使用对端加载的过滤器回复过滤后的区块
+		req, err := DecodeGetMerkleBlock(msg.Data)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		filter := readPeer.Filter()
+		if filter == nil {
+			return
+		}
+		block := blockchain.GetBlockchain().GetBlock(fmt.Sprintf("%x", req.BlockHash))
+		if block == nil {
+			return
+		}
+		merkleBlock, _ := json.Marshal(NewMerkleBlock(block, filter))
+		readPeer.Send(EncodeMessage(MsgTypeMerkleBlock, merkleBlock))
+
+	case MsgTypeMerkleBlock:
+		// 轻节点校验过滤后的区块,只接受已向该peer请求的区块
+		merkleBlock, err := DecodeMerkleBlock(msg.Data)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if !readPeer.takeMerkleRequest(merkleBlock.Header.Hash, time.Now()) {
+			return
+		}
+		if err := merkleBlock.Verify(); err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Printf("merkle block %x: %d matched transactions\n", merkleBlock.Header.Hash, len(merkleBlock.Transactions))
+
+	case MsgTypeGetFeeEstimate:
+		req, err := DecodeGetFeeEstimate(msg.Data)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		// 回复本节点的估算结果
+		estimator := transaction.GetFeeEstimator()
+		if estimator == nil {
+			return
+		}
+		estimate, err := estimator.EstimateFee(req.Target, req.Confidence)
+		if err != nil {
+			return
+		}
+		data, _ := json.Marshal(estimate)
+		readPeer.Send(EncodeMessage(MsgTypeFeeEstimate, data))
 
-		s.Broadcast(MsgTypeWallet, msg.Data, readPeer)
+	case MsgTypeFeeEstimate:
+		estimate, err := DecodeFeeEstimate(msg.Data)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Printf("peer %s fee estimate: %s within %d blocks (%.2f)\n", readPeer.ID, estimate.FeeRate, estimate.Target, estimate.Confidence)
 
 	case MsgTypePing:
 		return
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/Alan-333333/simple-blockchain/signer"
+	"github.com/Alan-333333/simple-blockchain/transaction"
+	"github.com/Alan-333333/simple-blockchain/wallet"
+)
+
+// Signer daemon holding wallet keys outside the node process.
+//
+//	signerd -socket ./dat/signer.sock -maxAmount 10 -allow addr1,addr2 address...
+//
+// Wallets are read from ./dat/wallet of the working directory and
+// unlocked with passphrases read from stdin, one line per address.
+func main() {
+
+	socket := flag.String("socket", "./dat/signer.sock", "unix socket to listen on")
+	maxAmount := flag.String("maxAmount", "", "largest amount allowed per transaction")
+	maxFee := flag.String("maxFee", "", "largest fee allowed per transaction")
+	allow := flag.String("allow", "", "comma separated recipient addresses, empty allows any")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		log.Fatal("no wallet addresses given")
+	}
+
+	// Build signing policy
+	var policy signer.Policy
+	var err error
+	if *maxAmount != "" {
+		if policy.MaxAmount, err = transaction.ParseAmount(*maxAmount); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *maxFee != "" {
+		if policy.MaxFee, err = transaction.ParseAmount(*maxFee); err != nil {
+			log.Fatal(err)
+		}
+	}
+	for _, address := range strings.Split(*allow, ",") {
+		if address == "" {
+			continue
+		}
+		if err := policy.AllowRecipient(address); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Unlock wallet keys
+	server := signer.NewServer(policy)
+	reader := bufio.NewReader(os.Stdin)
+	for _, address := range flag.Args() {
+		fmt.Printf("passphrase for %s: ", address)
+		passphrase, err := reader.ReadString('\n')
+		if err != nil {
+			log.Fatal(err)
+		}
+		w, err := wallet.Unlock(address, strings.TrimRight(passphrase, "\r\n"), 0)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if _, err := server.AddKey(w.PrivateKey); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Serve signing requests
+	listener, err := signer.ListenUnix(*socket)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("signer listening on", *socket)
+	log.Fatal(server.Serve(listener))
+}
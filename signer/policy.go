@@ -0,0 +1,59 @@
+package signer
+
+import (
+	"fmt"
+
+	"github.com/Alan-333333/simple-blockchain/transaction"
+	"github.com/Alan-333333/simple-blockchain/utils"
+)
+
+// ErrPolicy 交易不符合签名策略
+type ErrPolicy struct {
+	Reason string
+}
+
+func (e ErrPolicy) Error() string {
+	return "signing policy: " + e.Reason
+}
+
+// Policy 签名进程签名前对交易的检查
+type Policy struct {
+	MaxAmount         transaction.Amount // 单笔交易金额上限,0表示不限制
+	MaxFee            transaction.Amount // 单笔交易手续费上限,0表示不限制
+	AllowedRecipients map[string]bool    // 允许的接收方地址,为空时不限制
+}
+
+// AllowRecipient 将地址加入接收方白名单
+func (p *Policy) AllowRecipient(address string) error {
+
+	// 白名单只保存规范格式的地址
+	address, err := utils.NormalizeAddress(address)
+	if err != nil {
+		return err
+	}
+	if p.AllowedRecipients == nil {
+		p.AllowedRecipients = make(map[string]bool)
+	}
+	p.AllowedRecipients[address] = true
+
+	return nil
+}
+
+// Check 检查交易是否符合策略
+func (p *Policy) Check(tx *transaction.Transaction) error {
+
+	if p.MaxAmount > 0 && tx.Value > p.MaxAmount {
+		return ErrPolicy{Reason: fmt.Sprintf("amount %s exceeds limit %s", tx.Value, p.MaxAmount)}
+	}
+	if p.MaxFee > 0 && tx.Fee > p.MaxFee {
+		return ErrPolicy{Reason: fmt.Sprintf("fee %s exceeds limit %s", tx.Fee, p.MaxFee)}
+	}
+	if len(p.AllowedRecipients) > 0 {
+		recipient, err := utils.NormalizeAddress(tx.Recipient)
+		if err != nil || !p.AllowedRecipients[recipient] {
+			return ErrPolicy{Reason: fmt.Sprintf("recipient %s is not allowed", tx.Recipient)}
+		}
+	}
+
+	return nil
+}
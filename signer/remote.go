@@ -0,0 +1,97 @@
+package signer
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/Alan-333333/simple-blockchain/transaction"
+	"github.com/Alan-333333/simple-blockchain/utils"
+)
+
+// 连接签名进程和等待响应的超时时间
+const REMOTE_TIMEOUT = 10 * time.Second
+
+// RemoteSigner 通过Unix域套接字请求签名进程签名,本进程不持有私钥
+type RemoteSigner struct {
+	path    string
+	address string
+}
+
+// NewRemoteSigner 连接签名进程,确认其持有地址的私钥
+func NewRemoteSigner(path, address string) (*RemoteSigner, error) {
+
+	address, err := utils.NormalizeAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	s := &RemoteSigner{path: path, address: address}
+
+	// 公钥必须对应该地址
+	resp, err := s.call(&request{Method: METHOD_PUBLIC_KEY, Address: address})
+	if err != nil {
+		return nil, err
+	}
+	pubKey, err := utils.DecodePubKey(resp.PubKey)
+	if err != nil {
+		return nil, err
+	}
+	if utils.PubKeyToAddr(pubKey) != address {
+		return nil, errors.New("remote signer public key does not match address")
+	}
+
+	return s, nil
+}
+
+// Address 签名进程中私钥对应的地址
+func (s *RemoteSigner) Address() string {
+	return s.address
+}
+
+// SignTx 请求签名进程签名交易,返回的签名在本地验证后才设置到交易上
+func (s *RemoteSigner) SignTx(tx *transaction.Transaction) error {
+
+	if tx.Sender != s.address {
+		return transaction.ErrSignerAddress
+	}
+
+	resp, err := s.call(&request{Method: METHOD_SIGN_TX, Tx: tx.Serialize()})
+	if err != nil {
+		return err
+	}
+
+	// 签名进程不可信时也不会得到无效的交易
+	signed := *tx
+	signed.PubKey, signed.Signature = resp.PubKey, resp.Signature
+	if !signed.IsValid() {
+		return errors.New("remote signer returned an invalid signature")
+	}
+	tx.PubKey, tx.Signature = signed.PubKey, signed.Signature
+
+	return nil
+}
+
+// 发送请求并等待响应
+func (s *RemoteSigner) call(req *request) (*response, error) {
+
+	conn, err := net.DialTimeout("unix", s.path, REMOTE_TIMEOUT)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(REMOTE_TIMEOUT))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, err
+	}
+	var resp response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, errors.New("remote signer: " + resp.Error)
+	}
+
+	return &resp, nil
+}
@@ -0,0 +1,197 @@
+package signer
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/Alan-333333/simple-blockchain/transaction"
+	"github.com/Alan-333333/simple-blockchain/utils"
+)
+
+// 签名进程支持的请求
+const (
+	METHOD_PUBLIC_KEY = "publicKey"
+	METHOD_SIGN_TX    = "signTx"
+)
+
+var ErrUnknownAddress = errors.New("signer has no key for address")
+
+// 请求和响应,每个连接上按行发送JSON
+type request struct {
+	Method  string
+	Address string `json:",omitempty"`
+	Tx      []byte `json:",omitempty"` // 交易的规范编码
+}
+
+type response struct {
+	PubKey    []byte `json:",omitempty"` // 带类型的公钥编码
+	Signature []byte `json:",omitempty"`
+	Error     string `json:",omitempty"`
+}
+
+// Server 签名进程,私钥只保存在该进程中,签名前按策略检查交易
+type Server struct {
+	policy Policy
+
+	mu   sync.Mutex
+	keys map[string]*transaction.LocalSigner
+}
+
+// NewServer 创建使用指定策略的签名进程
+func NewServer(policy Policy) *Server {
+	return &Server{
+		policy: policy,
+		keys:   make(map[string]*transaction.LocalSigner),
+	}
+}
+
+// AddKey 添加签名私钥,返回私钥对应的地址
+func (s *Server) AddKey(key crypto.Signer) (string, error) {
+
+	local, err := transaction.NewLocalSigner(key)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[local.Address()] = local
+
+	return local.Address(), nil
+}
+
+// ListenUnix 监听Unix域套接字,只有当前用户可以连接
+// 套接字必须放在只有当前用户可以访问的目录中,创建套接字和chmod之间其他用户无法连接
+func ListenUnix(path string) (net.Listener, error) {
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() || info.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("socket directory %s must only be accessible by the current user (chmod 700)", dir)
+	}
+
+	// 删除上次运行留下的套接字文件
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	return listener, nil
+}
+
+// Serve 处理连接上的签名请求,直到监听关闭
+func (s *Server) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+// 处理一个连接上的请求
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	decoder := json.NewDecoder(conn)
+	encoder := json.NewEncoder(conn)
+	for {
+		var req request
+		if err := decoder.Decode(&req); err != nil {
+			return
+		}
+
+		resp, err := s.process(&req)
+		if err != nil {
+			resp = &response{Error: err.Error()}
+		}
+		if err := encoder.Encode(resp); err != nil {
+			return
+		}
+	}
+}
+
+// 执行请求
+func (s *Server) process(req *request) (*response, error) {
+
+	switch req.Method {
+	case METHOD_PUBLIC_KEY:
+		return s.publicKey(req.Address)
+	case METHOD_SIGN_TX:
+		return s.signTx(req.Tx)
+	}
+
+	return nil, errors.New("unknown method")
+}
+
+// 查询地址的公钥
+func (s *Server) publicKey(address string) (*response, error) {
+
+	s.mu.Lock()
+	local := s.keys[address]
+	s.mu.Unlock()
+	if local == nil {
+		return nil, ErrUnknownAddress
+	}
+
+	pubKey, err := utils.EncodePubKey(local.PublicKey())
+	if err != nil {
+		return nil, err
+	}
+
+	return &response{PubKey: pubKey}, nil
+}
+
+// 检查策略并签名交易
+func (s *Server) signTx(data []byte) (*response, error) {
+
+	// 1. 解析交易
+	tx, err := transaction.DeserializeTransaction(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := utils.ValidateAddress(tx.Recipient); err != nil {
+		return nil, err
+	}
+
+	// 2. 发送方的私钥
+	s.mu.Lock()
+	local := s.keys[tx.Sender]
+	s.mu.Unlock()
+	if local == nil {
+		return nil, ErrUnknownAddress
+	}
+
+	// 3. 策略检查
+	if err := s.policy.Check(tx); err != nil {
+		return nil, err
+	}
+
+	// 4. 签名
+	if err := local.SignTx(tx); err != nil {
+		return nil, err
+	}
+
+	return &response{PubKey: tx.PubKey, Signature: tx.Signature}, nil
+}
@@ -0,0 +1,165 @@
+package signer
+
+import (
+	"crypto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Alan-333333/simple-blockchain/transaction"
+	"github.com/Alan-333333/simple-blockchain/utils"
+)
+
+// 启动签名进程,返回套接字路径
+func startServer(t *testing.T, policy Policy, keys ...utils.KeyType) (string, []string) {
+
+	// Unix套接字路径长度有限,不使用t.TempDir
+	dir, err := os.MkdirTemp("", "signer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "signer.sock")
+
+	server := NewServer(policy)
+	var addresses []string
+	for _, keyType := range keys {
+		key, err := utils.GenerateKey(keyType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		address, err := server.AddKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addresses = append(addresses, address)
+	}
+
+	listener, err := ListenUnix(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go server.Serve(listener)
+
+	return path, addresses
+}
+
+func TestRemoteSigner(t *testing.T) {
+
+	recipient := utils.PubKeyToAddr(mustKey(t).Public())
+	path, addresses := startServer(t, Policy{}, utils.KeyTypeP256, utils.KeyTypeSecp256k1, utils.KeyTypeEd25519)
+
+	for _, address := range addresses {
+
+		// 1. 连接时确认签名进程持有私钥
+		remote, err := NewRemoteSigner(path, address)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// 2. 签名结果与交易匹配
+		tx := transaction.NewTransaction(address, recipient, transaction.COIN, 1000, 0)
+		if err := remote.SignTx(tx); err != nil {
+			t.Fatal(err)
+		}
+		if !tx.IsValid() {
+			t.Errorf("remote signature not valid for %s", address)
+		}
+
+		// 3. 不签名其他地址的交易
+		other := transaction.NewTransaction(recipient, address, transaction.COIN, 0, 0)
+		if err := remote.SignTx(other); err != transaction.ErrSignerAddress {
+			t.Errorf("signing other sender got %v", err)
+		}
+	}
+
+	// 4. 签名进程没有的私钥
+	if _, err := NewRemoteSigner(path, recipient); err == nil || !strings.Contains(err.Error(), ErrUnknownAddress.Error()) {
+		t.Errorf("unknown address got %v", err)
+	}
+
+	// 5. 签名进程未运行
+	if _, err := NewRemoteSigner(path+".missing", addresses[0]); err == nil {
+		t.Errorf("connecting to missing socket succeeded")
+	}
+}
+
+func TestSignerPolicy(t *testing.T) {
+
+	allowed := utils.PubKeyToAddr(mustKey(t).Public())
+	other := utils.PubKeyToAddr(mustKey(t).Public())
+
+	policy := Policy{MaxAmount: 5 * transaction.COIN, MaxFee: 10000}
+	if err := policy.AllowRecipient(allowed); err != nil {
+		t.Fatal(err)
+	}
+	path, addresses := startServer(t, policy, utils.KeyTypeP256)
+	remote, err := NewRemoteSigner(path, addresses[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		recipient string
+		value     transaction.Amount
+		fee       transaction.Amount
+		ok        bool
+	}{
+		{allowed, 5 * transaction.COIN, 10000, true},
+		{allowed, 5*transaction.COIN + 1, 0, false},
+		{allowed, transaction.COIN, 10001, false},
+		{other, transaction.COIN, 0, false},
+	}
+	for _, test := range tests {
+		tx := transaction.NewTransaction(addresses[0], test.recipient, test.value, test.fee, 0)
+		err := remote.SignTx(tx)
+		if test.ok && err != nil {
+			t.Errorf("%s %s to %s rejected: %v", test.value, test.fee, test.recipient, err)
+		}
+		if !test.ok && (err == nil || len(tx.Signature) > 0) {
+			t.Errorf("%s %s to %s not rejected by policy", test.value, test.fee, test.recipient)
+		}
+	}
+}
+
+func mustKey(t *testing.T) crypto.Signer {
+	key, err := utils.GenerateKey(utils.KeyTypeP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestListenUnixPermissions(t *testing.T) {
+
+	dir, err := os.MkdirTemp("", "signer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	// 1. 创建的目录和套接字只有当前用户可以访问
+	path := filepath.Join(dir, "sub", "signer.sock")
+	listener, err := ListenUnix(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener.Close()
+	if info, err := os.Stat(filepath.Dir(path)); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("socket directory mode %v, %v", info.Mode().Perm(), err)
+	}
+
+	// 2. 其他用户可以访问的目录中不创建套接字
+	open := filepath.Join(dir, "open")
+	if err := os.Mkdir(open, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(open, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ListenUnix(filepath.Join(open, "signer.sock")); err == nil {
+		t.Errorf("listening in a shared directory succeeded")
+	}
+}
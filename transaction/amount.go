@@ -0,0 +1,137 @@
+package transaction
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Amount 以最小单位计数的金额,类似比特币的聪
+type Amount int64
+
+// 一个币对应的最小单位数量
+const COIN Amount = 100000000
+
+// 最小单位的小数位数
+const AMOUNT_DECIMALS = 8
+
+// 金额上限
+const MAX_MONEY Amount = 21000000 * COIN
+
+var (
+	ErrAmountOutOfRange = errors.New("amount out of range")
+	ErrInvalidAmount    = errors.New("invalid amount")
+)
+
+// IsValid 金额是否在[0, MAX_MONEY]范围内
+func (a Amount) IsValid() bool {
+	return a >= 0 && a <= MAX_MONEY
+}
+
+// Add 加法,结果超出范围时返回错误
+func (a Amount) Add(b Amount) (Amount, error) {
+	if !a.IsValid() || !b.IsValid() {
+		return 0, ErrAmountOutOfRange
+	}
+
+	// 两个合法金额相加不会溢出int64
+	sum := a + b
+	if !sum.IsValid() {
+		return 0, ErrAmountOutOfRange
+	}
+	return sum, nil
+}
+
+// Sub 减法,结果为负或超出范围时返回错误
+func (a Amount) Sub(b Amount) (Amount, error) {
+	if !a.IsValid() || !b.IsValid() {
+		return 0, ErrAmountOutOfRange
+	}
+
+	diff := a - b
+	if !diff.IsValid() {
+		return 0, ErrAmountOutOfRange
+	}
+	return diff, nil
+}
+
+// String 格式化为十进制字符串,如"10.5"
+func (a Amount) String() string {
+
+	sign := ""
+	u := uint64(a)
+	if a < 0 {
+		sign = "-"
+		u = uint64(-a)
+	}
+
+	coin := uint64(COIN)
+	whole := strconv.FormatUint(u/coin, 10)
+	frac := u % coin
+	if frac == 0 {
+		return sign + whole
+	}
+
+	// 补齐小数位并去掉末尾的0
+	fracStr := strconv.FormatUint(frac, 10)
+	fracStr = strings.Repeat("0", AMOUNT_DECIMALS-len(fracStr)) + fracStr
+	fracStr = strings.TrimRight(fracStr, "0")
+
+	return sign + whole + "." + fracStr
+}
+
+// ParseAmount 解析十进制字符串金额,最多8位小数
+func ParseAmount(s string) (Amount, error) {
+
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, ErrInvalidAmount
+	}
+
+	// 1. 拆分整数和小数部分
+	whole, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+	}
+	if whole == "" && frac == "" {
+		return 0, ErrInvalidAmount
+	}
+	if len(frac) > AMOUNT_DECIMALS {
+		return 0, ErrInvalidAmount
+	}
+
+	// 只允许数字,不允许符号
+	for _, c := range whole + frac {
+		if c < '0' || c > '9' {
+			return 0, ErrInvalidAmount
+		}
+	}
+
+	// 2. 整数部分
+	var wholeUnits uint64
+	if whole != "" {
+		w, err := strconv.ParseUint(whole, 10, 64)
+		if err != nil || w > uint64(MAX_MONEY/COIN) {
+			return 0, ErrAmountOutOfRange
+		}
+		wholeUnits = w
+	}
+
+	// 3. 小数部分补齐到8位
+	var fracUnits uint64
+	if frac != "" {
+		frac += strings.Repeat("0", AMOUNT_DECIMALS-len(frac))
+		f, err := strconv.ParseUint(frac, 10, 64)
+		if err != nil {
+			return 0, ErrInvalidAmount
+		}
+		fracUnits = f
+	}
+
+	amount := Amount(wholeUnits)*COIN + Amount(fracUnits)
+	if !amount.IsValid() {
+		return 0, ErrAmountOutOfRange
+	}
+
+	return amount, nil
+}
@@ -0,0 +1,71 @@
+package transaction
+
+import (
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+
+	cases := []struct {
+		input string
+		want  Amount
+	}{
+		{"10", 10 * COIN},
+		{"0.1", COIN / 10},
+		{"1.23456789", 123456789},
+		{".5", COIN / 2},
+		{"21000000", MAX_MONEY},
+	}
+
+	for _, c := range cases {
+		got, err := ParseAmount(c.input)
+		if err != nil {
+			t.Errorf("parse %s failed: %v", c.input, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("parse %s got %d want %d", c.input, got, c.want)
+		}
+	}
+
+	// 非法输入
+	for _, input := range []string{"", ".", "-1", "1e5", "0.123456789", "21000000.00000001", "abc"} {
+		if _, err := ParseAmount(input); err == nil {
+			t.Errorf("parse %q should fail", input)
+		}
+	}
+}
+
+func TestAmountString(t *testing.T) {
+
+	cases := map[Amount]string{
+		0:              "0",
+		10 * COIN:      "10",
+		COIN / 2:       "0.5",
+		123456789:      "1.23456789",
+		-COIN - COIN/4: "-1.25",
+	}
+
+	for amount, want := range cases {
+		if got := amount.String(); got != want {
+			t.Errorf("format %d got %s want %s", int64(amount), got, want)
+		}
+	}
+}
+
+func TestAmountArithmetic(t *testing.T) {
+
+	// 0.1 + 0.2 精确等于 0.3
+	sum, err := (COIN / 10).Add(COIN / 5)
+	if err != nil || sum.String() != "0.3" {
+		t.Errorf("0.1 + 0.2 got %s, %v", sum, err)
+	}
+
+	if _, err := MAX_MONEY.Add(1); err == nil {
+		t.Error("adding past MAX_MONEY should fail")
+	}
+
+	if _, err := Amount(1).Sub(2); err == nil {
+		t.Error("negative result should fail")
+	}
+}
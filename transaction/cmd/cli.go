@@ -16,7 +16,7 @@ func main() {
 	// 2. 创建交易
 	address := utils.PubKeyToAddr(pubKey)
 
-	txs := transaction.NewTransaction(address, "receiver", 10)
+	txs := transaction.NewTransaction(address, "receiver", 10, 0, 0)
 
 	// 3. 签名交易
 	txs.Sign(privKey)
@@ -30,7 +30,7 @@ func main() {
 		Version:   blockchain.CURRENT_BLOCK_VERSION,
 		Timestamp: uint64(time.Now().Unix()),
 	}
-	genesisBlock.Transactions = pool.Txs
+	genesisBlock.Transactions = pool.GetTxs()
 
 	// 6. 持久化
 	// pool.Save()
@@ -0,0 +1,183 @@
+package transaction
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+)
+
+// 交易编码版本,编码格式或签名hash变化时必须递增
+//
+//	version 1: 地址、金额、手续费、序号和标志位,没有公钥,发送方地址为公钥编码
+//	version 2: 增加未带类型的压缩公钥,发送方地址为公钥hash
+//	version 3: 公钥带密钥类型前缀
+//
+// 旧版本的交易无法按当前规则验证签名,解码时返回ErrUnsupportedTxVersion
+const CURRENT_TX_VERSION = 3
+
+var ErrUnsupportedTxVersion = errors.New("unsupported transaction version")
+
+// 单个变长字段的最大长度
+const maxFieldLen = 1 << 16
+
+// 签名的最大长度,DER编码的ECDSA签名最长72字节,Ed25519签名为64字节
+const MAX_SIGNATURE_LEN = 72
+
+// 带类型的公钥编码的最大长度: 类型 + 33字节压缩公钥
+const MAX_PUBKEY_LEN = 34
+
+// 交易标志位
+const txFlagReplaceable uint8 = 1
+
+// 交易编码格式(version 3,整数均为小端序):
+//
+//	version    uint32
+//	sender     uint32长度 + 字节
+//	pubkey     uint32长度 + 字节(密钥类型 + 公钥编码)
+//	recipient  uint32长度 + 字节
+//	value      int64(最小单位)
+//	fee        int64(最小单位)
+//	nonce      uint64
+//	flags      uint8(bit0: replaceable)
+//	signature  uint32长度 + 字节(ECDSA为DER,Ed25519为64字节)
+//
+// 签名hash(sighash)使用相同格式,但不包含signature字段。
+
+// Serialize 交易的规范二进制编码
+func (tx *Transaction) Serialize() []byte {
+	buf := new(bytes.Buffer)
+	tx.encodeUnsigned(buf)
+	writeBytes(buf, tx.Signature)
+	return buf.Bytes()
+}
+
+// DeserializeTransaction 从规范二进制编码解码交易
+func DeserializeTransaction(data []byte) (*Transaction, error) {
+
+	buf := bytes.NewReader(data)
+	tx := &Transaction{}
+
+	// 1. 版本号
+	if err := binary.Read(buf, binary.LittleEndian, &tx.Version); err != nil {
+		return nil, err
+	}
+	if tx.Version != CURRENT_TX_VERSION {
+		return nil, ErrUnsupportedTxVersion
+	}
+
+	// 2. 地址和公钥
+	sender, err := readBytes(buf)
+	if err != nil {
+		return nil, err
+	}
+	if tx.PubKey, err = readBytes(buf); err != nil {
+		return nil, err
+	}
+	recipient, err := readBytes(buf)
+	if err != nil {
+		return nil, err
+	}
+	tx.Sender = string(sender)
+	tx.Recipient = string(recipient)
+
+	// 3. 金额、手续费和序号
+	if err := binary.Read(buf, binary.LittleEndian, &tx.Value); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(buf, binary.LittleEndian, &tx.Fee); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(buf, binary.LittleEndian, &tx.Nonce); err != nil {
+		return nil, err
+	}
+
+	// 标志位,未定义的位必须为0
+	var flags uint8
+	if err := binary.Read(buf, binary.LittleEndian, &flags); err != nil {
+		return nil, err
+	}
+	if flags&^txFlagReplaceable != 0 {
+		return nil, errors.New("unknown transaction flags")
+	}
+	tx.Replaceable = flags&txFlagReplaceable != 0
+
+	// 4. 签名
+	if tx.Signature, err = readBytes(buf); err != nil {
+		return nil, err
+	}
+
+	// 不允许多余的数据
+	if buf.Len() != 0 {
+		return nil, errors.New("trailing bytes after transaction")
+	}
+
+	return tx, nil
+}
+
+// ID 交易ID,规范编码的双SHA256
+func (tx *Transaction) ID() []byte {
+	return doubleSha256(tx.Serialize())
+}
+
+// IDHex 十六进制的交易ID
+func (tx *Transaction) IDHex() string {
+	return hex.EncodeToString(tx.ID())
+}
+
+// SigHash 签名使用的hash,不包含签名的编码的双SHA256
+func (tx *Transaction) SigHash() []byte {
+	buf := new(bytes.Buffer)
+	tx.encodeUnsigned(buf)
+	return doubleSha256(buf.Bytes())
+}
+
+// 编码除签名外的字段
+func (tx *Transaction) encodeUnsigned(buf *bytes.Buffer) {
+	binary.Write(buf, binary.LittleEndian, tx.Version)
+	writeBytes(buf, []byte(tx.Sender))
+	writeBytes(buf, tx.PubKey)
+	writeBytes(buf, []byte(tx.Recipient))
+	binary.Write(buf, binary.LittleEndian, int64(tx.Value))
+	binary.Write(buf, binary.LittleEndian, int64(tx.Fee))
+	binary.Write(buf, binary.LittleEndian, tx.Nonce)
+
+	var flags uint8
+	if tx.Replaceable {
+		flags |= txFlagReplaceable
+	}
+	buf.WriteByte(flags)
+}
+
+// 写入带长度前缀的字节
+func writeBytes(buf *bytes.Buffer, data []byte) {
+	binary.Write(buf, binary.LittleEndian, uint32(len(data)))
+	buf.Write(data)
+}
+
+// 读取带长度前缀的字节
+func readBytes(r *bytes.Reader) ([]byte, error) {
+
+	var length uint32
+	if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+		return nil, err
+	}
+	if length > maxFieldLen || int(length) > r.Len() {
+		return nil, errors.New("invalid field length")
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func doubleSha256(data []byte) []byte {
+	first := sha256.Sum256(data)
+	second := sha256.Sum256(first[:])
+	return second[:]
+}
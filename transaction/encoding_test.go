@@ -0,0 +1,119 @@
+package transaction
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+
+	"github.com/Alan-333333/simple-blockchain/utils"
+)
+
+func TestSerializeTransaction(t *testing.T) {
+
+	// 1. 构造并签名交易
+	privKey, pubKey := utils.GenerateKeyPair()
+	tx := NewTransaction(utils.PubKeyToAddr(pubKey), "receiver", 10, 1, 0)
+	if err := tx.Sign(privKey); err != nil {
+		t.Fatal(err)
+	}
+
+	// 2. 编解码
+	decoded, err := DeserializeTransaction(tx.Serialize())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(decoded.ID(), tx.ID()) {
+		t.Errorf("ID mismatch after decode")
+	}
+
+	if !decoded.IsValid() {
+		t.Errorf("decoded transaction should be valid")
+	}
+
+	// 3. 签名必须覆盖金额
+	decoded.Value = 11
+	if decoded.IsValid() {
+		t.Errorf("signature should commit to value")
+	}
+
+	// 公钥必须对应发送方地址
+	otherKey, _ := utils.GenerateKeyPair()
+	forged := NewTransaction(tx.Sender, "receiver", 10, 1, 0)
+	forged.Sign(otherKey)
+	if forged.IsValid() {
+		t.Errorf("public key must match sender address")
+	}
+
+	// 4. 多余的数据
+	if _, err := DeserializeTransaction(append(tx.Serialize(), 0)); err == nil {
+		t.Errorf("trailing bytes should be rejected")
+	}
+
+	// 5. 旧版本的交易
+	old := *tx
+	old.Version = CURRENT_TX_VERSION - 1
+	if _, err := DeserializeTransaction(old.Serialize()); err != ErrUnsupportedTxVersion {
+		t.Errorf("old transaction version got %v", err)
+	}
+}
+
+func TestSignKeyTypes(t *testing.T) {
+
+	for _, keyType := range []utils.KeyType{utils.KeyTypeP256, utils.KeyTypeSecp256k1, utils.KeyTypeEd25519} {
+
+		key, err := utils.GenerateKey(keyType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx := NewTransaction(utils.PubKeyToAddr(key.Public()), "receiver", 10, 1, 0)
+		if err := tx.Sign(key); err != nil {
+			t.Fatal(err)
+		}
+		if len(tx.PubKey) > MAX_PUBKEY_LEN || len(tx.Signature) > MAX_SIGNATURE_LEN {
+			t.Errorf("%v transaction exceeds size limits", keyType)
+		}
+
+		decoded, err := DeserializeTransaction(tx.Serialize())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !decoded.IsValid() {
+			t.Errorf("%v transaction should be valid", keyType)
+		}
+
+		// 公钥类型与地址版本号不一致
+		decoded.PubKey[0] = byte((keyType + 1) % 3)
+		if decoded.IsValid() {
+			t.Errorf("%v transaction valid with wrong key type", keyType)
+		}
+	}
+}
+
+func TestSignatureMalleability(t *testing.T) {
+
+	privKey, pubKey := utils.GenerateKeyPair()
+	tx := NewTransaction(utils.PubKeyToAddr(pubKey), "receiver", 10, 1, 0)
+	tx.Sign(privKey)
+
+	// 1. 签名是确定性的,重复签名得到相同的txid
+	again := NewTransaction(tx.Sender, "receiver", 10, 1, 0)
+	again.Sign(privKey)
+	if !bytes.Equal(tx.ID(), again.ID()) {
+		t.Errorf("signing is not deterministic")
+	}
+
+	// 2. 翻转S得到的签名不被接受
+	n := privKey.Curve.Params().N
+	r, s, err := utils.ParseSignature(tx.Signature, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flipped, _ := asn1.Marshal(struct{ R, S *big.Int }{r, new(big.Int).Sub(n, s)})
+	malleated := *tx
+	malleated.Signature = flipped
+	if malleated.IsValid() {
+		t.Errorf("high-S signature accepted")
+	}
+}
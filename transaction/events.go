@@ -0,0 +1,72 @@
+package transaction
+
+import (
+	"time"
+)
+
+// PoolEventType 交易池事件类型
+type PoolEventType int
+
+const (
+	// 交易加入交易池
+	EventAdded PoolEventType = iota
+	// 交易被相同序号的交易替换
+	EventReplaced
+	// 交易因交易池已满或冲突被驱逐
+	EventEvicted
+	// 交易超过存活时间被移除
+	EventExpired
+	// 交易被区块确认
+	EventConfirmed
+)
+
+func (t PoolEventType) String() string {
+	switch t {
+	case EventAdded:
+		return "added"
+	case EventReplaced:
+		return "replaced"
+	case EventEvicted:
+		return "evicted"
+	case EventExpired:
+		return "expired"
+	case EventConfirmed:
+		return "confirmed"
+	}
+	return "unknown"
+}
+
+// PoolEvent 交易池事件
+type PoolEvent struct {
+	Type PoolEventType
+	Tx   *Transaction
+	Time time.Time
+
+	// 事件发生时的区块高度
+	Height int
+}
+
+// Subscribe 订阅交易池事件
+// 订阅者处理不及时导致缓冲区已满时,新事件会被丢弃,不会阻塞交易池
+func (pool *TxPool) Subscribe(buffer int) <-chan PoolEvent {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	ch := make(chan PoolEvent, buffer)
+	pool.subscribers = append(pool.subscribers, ch)
+	return ch
+}
+
+// 通知所有订阅者,调用方需持有锁
+func (pool *TxPool) emit(eventType PoolEventType, tx *Transaction, now time.Time) {
+	event := PoolEvent{Type: eventType, Tx: tx, Time: now}
+	if pool.chain != nil {
+		event.Height = pool.chain.GetHeight()
+	}
+	for _, ch := range pool.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
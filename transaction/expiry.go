@@ -0,0 +1,69 @@
+package transaction
+
+import (
+	"time"
+)
+
+// 交易在交易池中的默认存活时间
+const MEMPOOL_EXPIRY = 14 * 24 * time.Hour
+
+// 过期交易ID的保留时间,期间拒绝重新加入
+const EXPIRED_TX_MEMORY = time.Hour
+
+// SetExpiry 设置交易的存活时间
+func (pool *TxPool) SetExpiry(ttl time.Duration) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	pool.ttl = ttl
+}
+
+// Expire 移除存活时间超过ttl的交易,返回被移除的交易
+// 同一发送方序号更大的交易无法再被打包,一并移除
+func (pool *TxPool) Expire(now time.Time) []*Transaction {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	// 1. 清理过期记录
+	for id := range pool.expired {
+		if !pool.recentlyExpired(id, now) {
+			delete(pool.expired, id)
+		}
+	}
+
+	// 2. 移除过期的交易
+	expired := []*Transaction{}
+	for _, entry := range pool.txs {
+		if !pool.isExpired(entry.added, now) {
+			continue
+		}
+		for _, e := range pool.removeWithDescendants(entry) {
+			pool.expired[e.id] = now
+			expired = append(expired, e.tx)
+			pool.emit(EventExpired, e.tx, now)
+		}
+	}
+
+	return expired
+}
+
+// RunExpiry 在后台定期移除过期的交易
+func (pool *TxPool) RunExpiry(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		pool.Expire(now)
+	}
+}
+
+// 交易是否超过存活时间,调用方需持有锁
+func (pool *TxPool) isExpired(added time.Time, now time.Time) bool {
+	return now.Sub(added) > pool.ttl
+}
+
+// 交易是否在最近过期,调用方需持有锁
+func (pool *TxPool) recentlyExpired(id string, now time.Time) bool {
+	expiredAt, ok := pool.expired[id]
+	return ok && now.Sub(expiredAt) < EXPIRED_TX_MEMORY
+}
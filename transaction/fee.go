@@ -0,0 +1,334 @@
+package transaction
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FeeRate 每千字节的手续费
+type FeeRate Amount
+
+// 没有足够统计数据时使用的默认费率
+const DEFAULT_FEE_RATE FeeRate = 1000
+
+// 默认的确认目标区块数和置信度
+const (
+	DEFAULT_CONF_TARGET    = 6
+	DEFAULT_FEE_CONFIDENCE = 0.85
+)
+
+// 手续费统计参数
+const (
+	// 支持的最大确认目标区块数
+	FEE_MAX_TARGET = 25
+
+	// 每个区块统计数据的衰减系数
+	feeDecay = 0.998
+
+	// 费率分组的起始值和间隔倍数
+	feeBucketStart   = 1000
+	feeBucketSpacing = 1.5
+	feeBucketCount   = 40
+
+	// 一组费率至少需要的样本数
+	feeSufficientTxs = 2
+
+	// 统计文件格式版本
+	feeStatsVersion = 1
+)
+
+// 手续费统计保存的文件名
+const feeEstimatesFile = "./dat/fee/estimates.json"
+
+var ErrInsufficientFeeData = errors.New("insufficient data for fee estimate")
+
+// NewFeeRate 根据手续费和交易字节数计算费率
+func NewFeeRate(fee Amount, size int) FeeRate {
+	if size <= 0 {
+		return 0
+	}
+	return FeeRate(int64(fee) * 1000 / int64(size))
+}
+
+// FeeFor 指定字节数的交易需要的手续费,向上取整
+func (r FeeRate) FeeFor(size int) Amount {
+	return Amount((int64(r)*int64(size) + 999) / 1000)
+}
+
+func (r FeeRate) String() string {
+	return Amount(r).String() + "/kB"
+}
+
+// FeeEstimate 手续费估算结果
+type FeeEstimate struct {
+	// 在Target个区块内确认需要的费率
+	FeeRate FeeRate
+
+	Target int
+
+	// 历史数据中该费率在Target个区块内确认的比例
+	Confidence float64
+}
+
+// 交易池中正在跟踪的交易
+type trackedTx struct {
+	bucket int
+	height int
+}
+
+// 可持久化的统计数据
+type feeStats struct {
+	Version    int
+	BestHeight int
+
+	// 费率分组的下界,第i组为[Buckets[i], Buckets[i+1])
+	Buckets []FeeRate
+
+	// Confirmed[t-1][b]: 第b组在t个区块内确认的交易数(衰减后)
+	Confirmed [][]float64
+
+	// Total[b]: 第b组已确认或离开交易池的交易数(衰减后)
+	Total []float64
+}
+
+// FeeEstimator 根据交易池交易的确认时间估算手续费
+type FeeEstimator struct {
+	stats   feeStats
+	tracked map[string]trackedTx
+
+	lock sync.Mutex
+}
+
+var feeEstimatorInstance *FeeEstimator
+
+// 创建手续费估算器
+func NewFeeEstimator() *FeeEstimator {
+
+	if feeEstimatorInstance != nil {
+		return feeEstimatorInstance
+	}
+	feeEstimatorInstance = newFeeEstimator()
+
+	return feeEstimatorInstance
+}
+
+func GetFeeEstimator() *FeeEstimator {
+	return feeEstimatorInstance
+}
+
+func newFeeEstimator() *FeeEstimator {
+
+	stats := feeStats{
+		Version:   feeStatsVersion,
+		Buckets:   make([]FeeRate, feeBucketCount),
+		Confirmed: make([][]float64, FEE_MAX_TARGET),
+		Total:     make([]float64, feeBucketCount),
+	}
+
+	// 第0组收集低于起始费率的交易
+	rate := float64(feeBucketStart)
+	for i := 1; i < feeBucketCount; i++ {
+		stats.Buckets[i] = FeeRate(rate)
+		rate *= feeBucketSpacing
+	}
+	for t := range stats.Confirmed {
+		stats.Confirmed[t] = make([]float64, feeBucketCount)
+	}
+
+	return &FeeEstimator{
+		stats:   stats,
+		tracked: make(map[string]trackedTx),
+	}
+}
+
+// Watch 订阅交易池事件并持续更新统计数据
+func (e *FeeEstimator) Watch(pool *TxPool) {
+	for event := range pool.Subscribe(1000) {
+		e.processEvent(event)
+	}
+}
+
+// 处理交易池事件
+func (e *FeeEstimator) processEvent(event PoolEvent) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.updateHeight(event.Height)
+
+	id := event.Tx.IDHex()
+	switch event.Type {
+	case EventAdded:
+		// 开始跟踪交易
+		rate := NewFeeRate(event.Tx.Fee, len(event.Tx.Serialize()))
+		e.tracked[id] = trackedTx{bucket: e.bucketIndex(rate), height: event.Height}
+
+	case EventConfirmed:
+		// 记录确认需要的区块数
+		tracked, ok := e.tracked[id]
+		if !ok {
+			return
+		}
+		delete(e.tracked, id)
+
+		blocks := event.Height - tracked.height
+		if blocks < 1 {
+			blocks = 1
+		}
+		for t := blocks; t <= FEE_MAX_TARGET; t++ {
+			e.stats.Confirmed[t-1][tracked.bucket]++
+		}
+		e.stats.Total[tracked.bucket]++
+
+	case EventEvicted, EventExpired:
+		// 未确认就离开交易池,记为失败
+		tracked, ok := e.tracked[id]
+		if !ok {
+			return
+		}
+		delete(e.tracked, id)
+		e.stats.Total[tracked.bucket]++
+
+	case EventReplaced:
+		delete(e.tracked, id)
+	}
+}
+
+// 新区块到来时衰减历史数据
+func (e *FeeEstimator) updateHeight(height int) {
+
+	// 高度降低(如重启后重新同步)时只更新高度
+	if height > e.stats.BestHeight {
+		decay := math.Pow(feeDecay, float64(height-e.stats.BestHeight))
+		for b := range e.stats.Total {
+			e.stats.Total[b] *= decay
+			for t := range e.stats.Confirmed {
+				e.stats.Confirmed[t][b] *= decay
+			}
+		}
+	}
+	e.stats.BestHeight = height
+}
+
+// 费率所在的分组
+func (e *FeeEstimator) bucketIndex(rate FeeRate) int {
+	idx := 0
+	for i, bound := range e.stats.Buckets {
+		if rate >= bound {
+			idx = i
+		}
+	}
+	return idx
+}
+
+// EstimateFee 估算在target个区块内以指定置信度确认需要的费率
+func (e *FeeEstimator) EstimateFee(target int, confidence float64) (*FeeEstimate, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if target < 1 || target > FEE_MAX_TARGET {
+		return nil, errors.New("fee estimate target out of range")
+	}
+
+	// 1. 仍在交易池中且已超过target个区块的交易视为失败
+	pending := make([]float64, feeBucketCount)
+	for _, tracked := range e.tracked {
+		if e.stats.BestHeight-tracked.height > target {
+			pending[tracked.bucket]++
+		}
+	}
+
+	// 2. 从最高费率开始向下合并分组,直到样本足够,
+	// 合并组的确认比例满足置信度时继续向下,否则停止
+	best := -1
+	bestConfidence := 0.0
+	var confirmed, total float64
+	for b := feeBucketCount - 1; b >= 0; b-- {
+		confirmed += e.stats.Confirmed[target-1][b]
+		total += e.stats.Total[b] + pending[b]
+		if total < feeSufficientTxs {
+			continue
+		}
+
+		ratio := confirmed / total
+		if ratio < confidence {
+			break
+		}
+		best = b
+		bestConfidence = ratio
+		confirmed, total = 0, 0
+	}
+
+	if best < 0 {
+		return nil, ErrInsufficientFeeData
+	}
+
+	// 3. 返回该分组的上界,保守估计
+	rate := e.stats.Buckets[best]
+	if best+1 < feeBucketCount {
+		rate = e.stats.Buckets[best+1]
+	}
+
+	return &FeeEstimate{FeeRate: rate, Target: target, Confidence: bestConfidence}, nil
+}
+
+// Save 将统计数据保存到数据目录
+func (e *FeeEstimator) Save() error {
+	return e.saveTo(feeEstimatesFile)
+}
+
+// Load 从数据目录加载统计数据,文件不存在时保持为空
+func (e *FeeEstimator) Load() error {
+	return e.loadFrom(feeEstimatesFile)
+}
+
+func (e *FeeEstimator) saveTo(path string) error {
+	e.lock.Lock()
+	data, err := json.Marshal(&e.stats)
+	e.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0600)
+}
+
+func (e *FeeEstimator) loadFrom(path string) error {
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var stats feeStats
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return err
+	}
+
+	// 分组和目标数量必须与当前版本一致
+	if stats.Version != feeStatsVersion || len(stats.Buckets) != feeBucketCount ||
+		len(stats.Total) != feeBucketCount || len(stats.Confirmed) != FEE_MAX_TARGET {
+		return errors.New("incompatible fee estimates file")
+	}
+	for _, row := range stats.Confirmed {
+		if len(row) != feeBucketCount {
+			return errors.New("incompatible fee estimates file")
+		}
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.stats = stats
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package transaction
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFeeEstimator(t *testing.T) {
+
+	e := newFeeEstimator()
+
+	// 1. 没有数据时无法估算
+	if _, err := e.EstimateFee(1, DEFAULT_FEE_CONFIDENCE); err != ErrInsufficientFeeData {
+		t.Errorf("empty estimator got %v", err)
+	}
+
+	// 2. 高费率交易下一个区块确认,低费率交易一直未确认后过期
+	var rate FeeRate
+	for i := 0; i < 10; i++ {
+		high := NewTransaction("alice", "bob", 10, 1000, uint64(i))
+		low := NewTransaction("bob", "alice", 10, 1, uint64(i))
+		rate = NewFeeRate(high.Fee, len(high.Serialize()))
+
+		e.processEvent(PoolEvent{Type: EventAdded, Tx: high, Height: i})
+		e.processEvent(PoolEvent{Type: EventAdded, Tx: low, Height: i})
+		e.processEvent(PoolEvent{Type: EventConfirmed, Tx: high, Height: i + 1})
+		e.processEvent(PoolEvent{Type: EventExpired, Tx: low, Height: i + 1})
+	}
+
+	estimate, err := e.EstimateFee(1, DEFAULT_FEE_CONFIDENCE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if estimate.FeeRate < rate || estimate.Confidence < DEFAULT_FEE_CONFIDENCE {
+		t.Errorf("estimate %s (%.2f) below confirmed rate %s", estimate.FeeRate, estimate.Confidence, rate)
+	}
+
+	if _, err := e.EstimateFee(FEE_MAX_TARGET+1, DEFAULT_FEE_CONFIDENCE); err == nil {
+		t.Errorf("out of range target should fail")
+	}
+
+	// 3. 保存后重新加载得到相同的估算
+	path := filepath.Join(t.TempDir(), "estimates.json")
+	if err := e.saveTo(path); err != nil {
+		t.Fatal(err)
+	}
+	restored := newFeeEstimator()
+	if err := restored.loadFrom(path); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := restored.EstimateFee(1, DEFAULT_FEE_CONFIDENCE)
+	if err != nil || reloaded.FeeRate != estimate.FeeRate {
+		t.Errorf("reloaded estimate got %v, %v", reloaded, err)
+	}
+}
@@ -0,0 +1,181 @@
+package transaction
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Alan-333333/simple-blockchain/utils"
+)
+
+// 待签名交易文件格式版本
+const PARTIAL_TX_VERSION = 1
+
+var (
+	ErrPartialTxVersion = errors.New("unsupported partial transaction version")
+	ErrPartialTxNetwork = errors.New("partial transaction is for another network")
+	ErrPartialTxSigned  = errors.New("partial transaction is already signed")
+	ErrPartialTxPending = errors.New("partial transaction is not signed")
+)
+
+// PartialTx 待签名交易,用于在联网节点创建交易并在离线机器上签名
+// 包含离线校验交易所需的链上数据:发送方的可用余额和链上的交易序号
+type PartialTx struct {
+	Version    int
+	Network    string // 地址网络前缀,防止在其他网络上签名
+	Tx         *Transaction
+	Balance    Amount // 创建时发送方的可用余额
+	ChainNonce uint64 // 创建时发送方在链上的下一个序号
+	Height     int    // 创建时的区块高度
+}
+
+// 文件中交易使用规范编码的十六进制
+type partialTxFile struct {
+	Version    int
+	Network    string
+	Tx         string
+	Balance    Amount
+	ChainNonce uint64
+	Height     int
+}
+
+// NewPartialTx 为未签名的交易创建待签名交易
+func NewPartialTx(tx *Transaction, balance Amount, chainNonce uint64, height int) *PartialTx {
+	return &PartialTx{
+		Version:    PARTIAL_TX_VERSION,
+		Network:    utils.NetworkHRP,
+		Tx:         tx,
+		Balance:    balance,
+		ChainNonce: chainNonce,
+		Height:     height,
+	}
+}
+
+// IsSigned 交易是否已签名
+func (p *PartialTx) IsSigned() bool {
+	return len(p.Tx.Signature) > 0
+}
+
+// Verify 校验交易内容,已签名时同时校验签名
+// 离线签名前应检查交易的接收方、金额和手续费
+func (p *PartialTx) Verify() error {
+
+	// 1. 版本和网络
+	if p.Version != PARTIAL_TX_VERSION {
+		return ErrPartialTxVersion
+	}
+	if p.Network != utils.NetworkHRP {
+		return ErrPartialTxNetwork
+	}
+
+	// 2. 地址
+	tx := p.Tx
+	if err := utils.ValidateAddress(tx.Sender); err != nil {
+		return fmt.Errorf("invalid sender: %v", err)
+	}
+	if normalized, err := utils.NormalizeAddress(tx.Recipient); err != nil || normalized != tx.Recipient {
+		return errors.New("invalid recipient")
+	}
+
+	// 3. 金额、手续费和序号
+	if tx.Value <= 0 || !tx.Value.IsValid() || !tx.Fee.IsValid() {
+		return ErrAmountOutOfRange
+	}
+	total, err := tx.Value.Add(tx.Fee)
+	if err != nil {
+		return err
+	}
+	if total > p.Balance {
+		return fmt.Errorf("insufficient funds: available %s, required %s", p.Balance, total)
+	}
+	if tx.Nonce < p.ChainNonce {
+		return ErrStaleNonce
+	}
+
+	// 4. 签名
+	if p.IsSigned() && !tx.IsValid() {
+		return errors.New("invalid transaction signature")
+	}
+
+	return nil
+}
+
+// EncodePartialTx 编码待签名交易
+func EncodePartialTx(p *PartialTx) ([]byte, error) {
+
+	file := partialTxFile{
+		Version:    p.Version,
+		Network:    p.Network,
+		Tx:         hex.EncodeToString(p.Tx.Serialize()),
+		Balance:    p.Balance,
+		ChainNonce: p.ChainNonce,
+		Height:     p.Height,
+	}
+
+	return json.MarshalIndent(file, "", "  ")
+}
+
+// DecodePartialTx 解码并校验待签名交易
+func DecodePartialTx(data []byte) (*PartialTx, error) {
+
+	var file partialTxFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	if file.Version != PARTIAL_TX_VERSION {
+		return nil, ErrPartialTxVersion
+	}
+
+	raw, err := hex.DecodeString(file.Tx)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := DeserializeTransaction(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &PartialTx{
+		Version:    file.Version,
+		Network:    file.Network,
+		Tx:         tx,
+		Balance:    file.Balance,
+		ChainNonce: file.ChainNonce,
+		Height:     file.Height,
+	}
+	if err := p.Verify(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// WritePartialTx 将待签名交易写入文件
+func WritePartialTx(path string, p *PartialTx) error {
+
+	data, err := EncodePartialTx(p)
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+	}
+
+	return os.WriteFile(path, data, 0600)
+}
+
+// ReadPartialTx 从文件读取待签名交易
+func ReadPartialTx(path string) (*PartialTx, error) {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodePartialTx(data)
+}
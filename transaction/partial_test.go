@@ -0,0 +1,61 @@
+package transaction
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Alan-333333/simple-blockchain/utils"
+)
+
+func TestPartialTx(t *testing.T) {
+
+	privKey, pubKey := utils.GenerateKeyPair()
+	_, recipientKey := utils.GenerateKeyPair()
+	sender, recipient := utils.PubKeyToAddr(pubKey), utils.PubKeyToAddr(recipientKey)
+
+	// 1. 未签名的交易写入文件
+	tx := NewTransaction(sender, recipient, 5*COIN, 1000, 3)
+	p := NewPartialTx(tx, 10*COIN, 3, 100)
+	path := filepath.Join(t.TempDir(), "tx.json")
+	if err := WritePartialTx(path, p); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := ReadPartialTx(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.IsSigned() || loaded.Tx.IDHex() != tx.IDHex() || loaded.Balance != 10*COIN {
+		t.Errorf("partial transaction changed after decode")
+	}
+
+	// 2. 签名后的文件包含有效签名
+	loaded.Tx.Sign(privKey)
+	WritePartialTx(path, loaded)
+	signed, err := ReadPartialTx(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !signed.IsSigned() || !signed.Tx.IsValid() {
+		t.Errorf("signed partial transaction not valid")
+	}
+
+	// 3. 被篡改的签名交易
+	signed.Tx.Value = 6 * COIN
+	if signed.Verify() == nil {
+		t.Errorf("tampered transaction verified")
+	}
+
+	// 4. 余额不足、旧序号和其他网络
+	if NewPartialTx(NewTransaction(sender, recipient, 10*COIN, 1, 3), 10*COIN, 3, 100).Verify() == nil {
+		t.Errorf("insufficient balance verified")
+	}
+	if NewPartialTx(NewTransaction(sender, recipient, 1, 1, 2), 10*COIN, 3, 100).Verify() != ErrStaleNonce {
+		t.Errorf("stale nonce verified")
+	}
+	other := NewPartialTx(tx, 10*COIN, 3, 100)
+	other.Network = utils.BECH32_HRP_TESTNET
+	if other.Verify() != ErrPartialTxNetwork {
+		t.Errorf("other network verified")
+	}
+}
@@ -0,0 +1,134 @@
+package transaction
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 交易池保存的文件名
+const mempoolFile = "./dat/mempool/mempool.dat"
+
+// 交易池文件格式版本,保存的交易编码版本变化时递增
+// version 1的文件只包含旧版本的交易,加载时整个文件被拒绝
+const MEMPOOL_FILE_VERSION = 2
+
+// 交易池文件格式(整数均为小端序):
+//
+//	version  uint32
+//	count    uint32
+//	count个条目:
+//	  added  int64(进入交易池的unix时间)
+//	  tx     uint32长度 + 交易规范编码
+
+// Save 将交易池保存到数据目录
+func (pool *TxPool) Save() error {
+	return pool.saveTo(mempoolFile)
+}
+
+// Load 从数据目录加载交易池,返回加载的交易数量
+// 每笔交易都会重新校验,过期、已确认或非法的交易被丢弃
+func (pool *TxPool) Load() (int, error) {
+	return pool.loadFrom(mempoolFile, time.Now())
+}
+
+func (pool *TxPool) saveTo(path string) error {
+
+	// 1. 在锁内复制条目
+	pool.lock.RLock()
+	entries := make([]*poolEntry, 0, len(pool.txs))
+	for _, entry := range pool.txs {
+		entries = append(entries, entry)
+	}
+	pool.lock.RUnlock()
+
+	// 2. 序列化
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, uint32(MEMPOOL_FILE_VERSION))
+	binary.Write(buf, binary.LittleEndian, uint32(len(entries)))
+	for _, entry := range entries {
+		binary.Write(buf, binary.LittleEndian, entry.added.Unix())
+		writeBytes(buf, entry.tx.Serialize())
+	}
+
+	// 3. 创建保存目录
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	// 4. 先写临时文件再重命名,避免写入中断损坏文件
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (pool *TxPool) loadFrom(path string, now time.Time) (int, error) {
+
+	// 1. 读取文件,文件不存在时视为空交易池
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// 2. 解析文件头
+	r := bytes.NewReader(raw)
+	var version, count uint32
+	if err := binary.Read(r, binary.LittleEndian, &version); err != nil {
+		return 0, err
+	}
+	if version != MEMPOOL_FILE_VERSION {
+		return 0, fmt.Errorf("unsupported mempool file version %d", version)
+	}
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return 0, err
+	}
+
+	pool.lock.RLock()
+	ttl := pool.ttl
+	pool.lock.RUnlock()
+
+	// 3. 逐条校验并加入交易池
+	loaded := 0
+	for i := uint32(0); i < count; i++ {
+		var addedUnix int64
+		if err := binary.Read(r, binary.LittleEndian, &addedUnix); err != nil {
+			return loaded, err
+		}
+		data, err := readBytes(r)
+		if err != nil {
+			return loaded, err
+		}
+
+		// 过期的交易
+		added := time.Unix(addedUnix, 0)
+		if now.Sub(added) > ttl {
+			continue
+		}
+
+		tx, err := DeserializeTransaction(data)
+		if err != nil || !tx.IsValid() {
+			continue
+		}
+
+		// 已确认或序号冲突的交易由AddTx拒绝
+		if err := pool.addTxAt(tx, added); err != nil {
+			continue
+		}
+		loaded++
+	}
+
+	if r.Len() != 0 {
+		return loaded, errors.New("trailing bytes in mempool file")
+	}
+
+	return loaded, nil
+}
@@ -1,7 +1,89 @@
 package transaction
 
+import (
+	"container/heap"
+	"errors"
+	"math/bits"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 交易池默认的字节数上限
+const DEFAULT_POOL_MAX_SIZE = 5 << 20
+
+// 每个发送方在交易池中的最大交易数
+const DEFAULT_MAX_TXS_PER_SENDER = 25
+
+// 替换交易每字节至少需要增加的手续费
+const MIN_RBF_FEE_INCREMENT Amount = 1
+
+var (
+	ErrStaleNonce  = errors.New("transaction nonce already used on chain")
+	ErrInvalidTx   = errors.New("transaction violates chain rules")
+	ErrDuplicateTx = errors.New("transaction already in pool")
+	ErrConflictTx  = errors.New("transaction conflicts with a pooled transaction")
+	ErrSenderLimit = errors.New("too many pending transactions from sender")
+	ErrPoolFull    = errors.New("transaction fee rate too low for full pool")
+
+	ErrReplacementFee  = errors.New("replacement transaction fee too low")
+	ErrRecentlyExpired = errors.New("transaction recently expired from pool")
+)
+
+// ChainState 交易池校验交易时需要的链上状态
+type ChainState interface {
+	// GetNonce 返回地址下一笔交易应使用的序号
+	GetNonce(address string) uint64
+
+	// GetHeight 返回当前区块高度
+	GetHeight() int
+
+	// IsValidTransaction 校验地址、金额等交易规则
+	IsValidTransaction(tx *Transaction) bool
+}
+
+// 交易池中的交易及其优先级信息
+type poolEntry struct {
+	tx    *Transaction
+	id    string
+	size  int
+	added time.Time // 进入交易池的时间
+}
+
+// 手续费率比较,a的费率是否低于b,使用128位乘法避免溢出
+func (a *poolEntry) lowerFeeRate(b *poolEntry) bool {
+	aHi, aLo := bits.Mul64(uint64(a.tx.Fee), uint64(b.size))
+	bHi, bLo := bits.Mul64(uint64(b.tx.Fee), uint64(a.size))
+	if aHi != bHi {
+		return aHi < bHi
+	}
+	return aLo < bLo
+}
+
 type TxPool struct {
-	Txs []*Transaction
+	// 按交易ID索引
+	txs map[string]*poolEntry
+
+	// 按发送方和序号索引
+	bySender map[string]map[uint64]*poolEntry
+
+	// 交易总字节数
+	size int
+
+	maxSize      int
+	maxPerSender int
+
+	// 交易存活时间,以及最近过期的交易ID和过期时间
+	ttl     time.Duration
+	expired map[string]time.Time
+
+	chain ChainState
+
+	// 事件订阅者
+	subscribers []chan PoolEvent
+
+	// 保护以上所有字段
+	lock sync.RWMutex
 }
 
 var txPoolInstance *TxPool
@@ -12,81 +94,408 @@ func NewTxPool() *TxPool {
 	if txPoolInstance != nil {
 		return txPoolInstance
 	}
-	txPoolInstance = &TxPool{
-		Txs: make([]*Transaction, 0),
-	}
+	txPoolInstance = newTxPool()
 
 	return txPoolInstance
 }
 
+func newTxPool() *TxPool {
+	return &TxPool{
+		txs:          make(map[string]*poolEntry),
+		bySender:     make(map[string]map[uint64]*poolEntry),
+		maxSize:      DEFAULT_POOL_MAX_SIZE,
+		maxPerSender: DEFAULT_MAX_TXS_PER_SENDER,
+		ttl:          MEMPOOL_EXPIRY,
+		expired:      make(map[string]time.Time),
+	}
+}
+
 func GetTxPool() *TxPool {
 	return txPoolInstance
 }
 
+// SetChainState 设置用于校验序号的链上状态
+func (pool *TxPool) SetChainState(chain ChainState) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	pool.chain = chain
+}
+
+// SetLimits 设置交易池字节数上限和每个发送方的交易数上限
+func (pool *TxPool) SetLimits(maxSize int, maxPerSender int) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	pool.maxSize = maxSize
+	pool.maxPerSender = maxPerSender
+
+	now := time.Now()
+	for _, entry := range pool.evict() {
+		pool.emit(EventEvicted, entry.tx, now)
+	}
+}
+
 // 添加新交易
-func (pool *TxPool) AddTx(tx *Transaction) {
-	pool.Txs = append(pool.Txs, tx)
+func (pool *TxPool) AddTx(tx *Transaction) error {
+	return pool.addTxAt(tx, time.Now())
+}
+
+// 添加交易并记录进入交易池的时间
+func (pool *TxPool) addTxAt(tx *Transaction, added time.Time) error {
+
+	id := tx.IDHex()
+	size := len(tx.Serialize())
+	now := time.Now()
+
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	// 1. 金额和手续费必须在合法范围内,负的手续费会被当作极高的费率
+	if !tx.Fee.IsValid() || tx.Value <= 0 || !tx.Value.IsValid() {
+		return ErrAmountOutOfRange
+	}
+
+	// 2. 按交易ID去重,最近过期的交易不允许立即重新加入
+	if _, ok := pool.txs[id]; ok {
+		return ErrDuplicateTx
+	}
+	if pool.recentlyExpired(id, now) {
+		return ErrRecentlyExpired
+	}
+
+	// 3. 按链上规则校验,拒绝链上已使用的序号
+	if pool.chain != nil && !pool.chain.IsValidTransaction(tx) {
+		return ErrInvalidTx
+	}
+	if pool.chain != nil && tx.Nonce < pool.chain.GetNonce(tx.Sender) {
+		return ErrStaleNonce
+	}
+
+	entry := &poolEntry{tx: tx, id: id, size: size, added: added}
+
+	// 4. 与池中交易冲突(相同发送方和序号)时尝试替换
+	senderTxs := pool.bySender[tx.Sender]
+	conflict, replacing := senderTxs[tx.Nonce]
+	if replacing {
+		if err := checkReplacement(conflict, entry); err != nil {
+			return err
+		}
+		pool.remove(conflict)
+	} else if len(senderTxs) >= pool.maxPerSender {
+		// 5. 每个发送方的交易数限制
+		return ErrSenderLimit
+	}
+
+	// 6. 加入交易池
+	pool.insert(entry)
+
+	// 7. 超出上限时驱逐费率最低的交易
+	// 新交易自身被驱逐时恢复被驱逐和被替换的交易,交易池保持不变
+	evicted := pool.evict()
+	if _, ok := pool.txs[id]; !ok {
+		for _, e := range evicted {
+			if e != entry {
+				pool.insert(e)
+			}
+		}
+		if replacing {
+			pool.insert(conflict)
+		}
+		return ErrPoolFull
+	}
+
+	// 8. 通知订阅者
+	if replacing {
+		pool.emit(EventReplaced, conflict.tx, now)
+	}
+	for _, e := range evicted {
+		pool.emit(EventEvicted, e.tx, now)
+	}
+	pool.emit(EventAdded, tx, now)
+
+	return nil
+}
+
+// 检查替换规则:原交易允许替换,新交易的手续费率更高,
+// 且手续费的增加至少能支付新交易自身的转发费用
+func checkReplacement(old *poolEntry, replacement *poolEntry) error {
+
+	// 原交易未声明可替换时视为冲突
+	if !old.tx.Replaceable {
+		return ErrConflictTx
+	}
+
+	// 1. 手续费率必须更高
+	if !old.lowerFeeRate(replacement) {
+		return ErrReplacementFee
+	}
+
+	// 2. 绝对手续费的增加
+	minFee, err := old.tx.Fee.Add(MIN_RBF_FEE_INCREMENT * Amount(replacement.size))
+	if err != nil || replacement.tx.Fee < minFee {
+		return ErrReplacementFee
+	}
+
+	return nil
+}
+
+// 插入索引,调用方需持有锁
+func (pool *TxPool) insert(entry *poolEntry) {
+	pool.txs[entry.id] = entry
+	if pool.bySender[entry.tx.Sender] == nil {
+		pool.bySender[entry.tx.Sender] = make(map[uint64]*poolEntry)
+	}
+	pool.bySender[entry.tx.Sender][entry.tx.Nonce] = entry
+	pool.size += entry.size
 }
 
-// 从池中获取交易
+// 从索引中删除,调用方需持有锁
+func (pool *TxPool) remove(entry *poolEntry) {
+	delete(pool.txs, entry.id)
+	senderTxs := pool.bySender[entry.tx.Sender]
+	delete(senderTxs, entry.tx.Nonce)
+	if len(senderTxs) == 0 {
+		delete(pool.bySender, entry.tx.Sender)
+	}
+	pool.size -= entry.size
+}
+
+// 删除交易以及同一发送方序号更大的交易,它们已无法被打包
+func (pool *TxPool) removeWithDescendants(entry *poolEntry) []*poolEntry {
+	removed := []*poolEntry{}
+	for nonce, e := range pool.bySender[entry.tx.Sender] {
+		if nonce >= entry.tx.Nonce {
+			pool.remove(e)
+			removed = append(removed, e)
+		}
+	}
+	return removed
+}
+
+// 超出字节数上限时驱逐费率最低的交易,返回被驱逐的交易
+func (pool *TxPool) evict() []*poolEntry {
+	evicted := []*poolEntry{}
+	for pool.size > pool.maxSize && len(pool.txs) > 0 {
+		var lowest *poolEntry
+		for _, entry := range pool.txs {
+			if lowest == nil || entry.lowerFeeRate(lowest) {
+				lowest = entry
+			}
+		}
+		evicted = append(evicted, pool.removeWithDescendants(lowest)...)
+	}
+	return evicted
+}
+
+// NextNonce 返回地址的下一个可用序号,包含交易池中待确认的交易
+func (pool *TxPool) NextNonce(address string) uint64 {
+	pool.lock.RLock()
+	defer pool.lock.RUnlock()
+
+	var nonce uint64
+	if pool.chain != nil {
+		nonce = pool.chain.GetNonce(address)
+	}
+
+	for {
+		if _, ok := pool.bySender[address][nonce]; !ok {
+			return nonce
+		}
+		nonce++
+	}
+}
+
+// 从池中获取优先级最高的交易,交易池为空时返回nil
 func (pool *TxPool) GetTx() *Transaction {
-	// 后入先出
-	tx := pool.Txs[len(pool.Txs)-1]
-	pool.Txs = pool.Txs[:len(pool.Txs)-1]
-	return tx
+	txs := pool.PopTransactions(1)
+	if len(txs) == 0 {
+		return nil
+	}
+	return txs[0]
 }
 
-// 获取交易池
+// 获取交易池中的交易,按手续费率从高到低排序
 func (pool *TxPool) GetTxs() []*Transaction {
-	return pool.Txs
+	pool.lock.RLock()
+	entries := make([]*poolEntry, 0, len(pool.txs))
+	for _, entry := range pool.txs {
+		entries = append(entries, entry)
+	}
+	pool.lock.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[j].lowerFeeRate(entries[i])
+	})
+
+	txs := make([]*Transaction, len(entries))
+	for i, entry := range entries {
+		txs[i] = entry.tx
+	}
+	return txs
+}
+
+// Range 遍历交易池的快照,fn返回false时停止
+// 遍历期间不持有锁,fn中可以调用交易池的其他方法
+func (pool *TxPool) Range(fn func(tx *Transaction) bool) {
+	for _, tx := range pool.GetTxs() {
+		if !fn(tx) {
+			return
+		}
+	}
 }
 
 // 获取交易池大小
 func (pool *TxPool) Size() int {
-	return len(pool.Txs)
+	pool.lock.RLock()
+	defer pool.lock.RUnlock()
+
+	return len(pool.txs)
+}
+
+// 获取交易池的字节数
+func (pool *TxPool) Bytes() int {
+	pool.lock.RLock()
+	defer pool.lock.RUnlock()
+
+	return pool.size
 }
 
 // 判断是否包含该交易
 func (pool *TxPool) Has(tx *Transaction) bool {
-	// 遍历查找
-	for _, t := range pool.Txs {
-		if t == tx {
-			return true
-		}
+	id := tx.IDHex()
+
+	pool.lock.RLock()
+	defer pool.lock.RUnlock()
+
+	_, ok := pool.txs[id]
+	return ok
+}
+
+// 根据十六进制交易ID获取交易
+func (pool *TxPool) GetTxByID(id string) *Transaction {
+	pool.lock.RLock()
+	defer pool.lock.RUnlock()
+
+	if entry, ok := pool.txs[id]; ok {
+		return entry.tx
 	}
-	return false
+	return nil
 }
 
 // PopTransactions 从交易池中弹出指定数量的交易
+// 按手续费率从高到低选择,同一发送方的交易按序号连续弹出
 func (pool *TxPool) PopTransactions(n int) []*Transaction {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
 
-	if n > len(pool.Txs) {
+	if n > len(pool.txs) {
 		// 交易池中交易不足
 		return nil
 	}
 
-	txs := pool.Txs[:n]
-	pool.Txs = pool.Txs[n:]
+	// 1. 每个发送方当前可打包的交易
+	ready := &entryHeap{}
+	for sender := range pool.bySender {
+		if entry := pool.readyEntry(sender, pool.startNonce(sender)); entry != nil {
+			heap.Push(ready, entry)
+		}
+	}
+
+	// 2. 每次选择费率最高的交易,并加入该发送方的下一笔交易
+	txs := []*Transaction{}
+	for len(txs) < n && ready.Len() > 0 {
+		entry := heap.Pop(ready).(*poolEntry)
+		txs = append(txs, entry.tx)
+
+		if next := pool.readyEntry(entry.tx.Sender, entry.tx.Nonce+1); next != nil {
+			heap.Push(ready, next)
+		}
+	}
+
+	if len(txs) < n {
+		// 可打包的交易不足
+		return nil
+	}
+
+	pool.removeTxs(txs)
 
 	return txs
 }
 
+// 发送方可打包的第一个序号
+func (pool *TxPool) startNonce(sender string) uint64 {
+	if pool.chain != nil {
+		return pool.chain.GetNonce(sender)
+	}
+
+	// 没有链上状态时从池中最小的序号开始
+	var lowest uint64
+	found := false
+	for nonce := range pool.bySender[sender] {
+		if !found || nonce < lowest {
+			lowest = nonce
+			found = true
+		}
+	}
+	return lowest
+}
+
+// 获取发送方指定序号的交易
+func (pool *TxPool) readyEntry(sender string, nonce uint64) *poolEntry {
+	return pool.bySender[sender][nonce]
+}
+
 // RemoveTransactions 从交易池中移除指定的交易
 func (pool *TxPool) RemoveTransactions(txs []*Transaction) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
 
-	result := []*Transaction{}
-	for _, tx := range pool.Txs {
-		removed := false
-		for _, rmTx := range txs {
-			if tx == rmTx {
-				removed = true
-				break
-			}
+	pool.removeTxs(txs)
+}
+
+// 按交易ID移除交易,调用方需持有锁
+func (pool *TxPool) removeTxs(txs []*Transaction) {
+	for _, tx := range txs {
+		if entry, ok := pool.txs[tx.IDHex()]; ok {
+			pool.remove(entry)
 		}
-		if !removed {
-			result = append(result, tx)
+	}
+}
+
+// RemoveConfirmed 移除区块确认的交易,以及与其序号冲突的交易
+func (pool *TxPool) RemoveConfirmed(txs []*Transaction) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	pool.removeTxs(txs)
+
+	now := time.Now()
+	for _, tx := range txs {
+		pool.emit(EventConfirmed, tx, now)
+	}
+
+	// 同一发送方序号不大于已确认序号的交易不再有效
+	for _, tx := range txs {
+		for nonce, entry := range pool.bySender[tx.Sender] {
+			if nonce <= tx.Nonce {
+				pool.remove(entry)
+				pool.emit(EventEvicted, entry.tx, now)
+			}
 		}
 	}
+}
+
+// 按手续费率排序的最大堆
+type entryHeap []*poolEntry
 
-	pool.Txs = result
+func (h entryHeap) Len() int            { return len(h) }
+func (h entryHeap) Less(i, j int) bool  { return h[j].lowerFeeRate(h[i]) }
+func (h entryHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *entryHeap) Push(x interface{}) { *h = append(*h, x.(*poolEntry)) }
+func (h *entryHeap) Pop() interface{} {
+	old := *h
+	entry := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return entry
 }
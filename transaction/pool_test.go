@@ -0,0 +1,343 @@
+package transaction
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Alan-333333/simple-blockchain/utils"
+)
+
+// 测试用的链上状态
+type testChain map[string]uint64
+
+func (c testChain) GetNonce(address string) uint64 {
+	return c[address]
+}
+
+func (c testChain) GetHeight() int {
+	return 0
+}
+
+// 接收方为"invalid"的交易不符合链上规则
+func (c testChain) IsValidTransaction(tx *Transaction) bool {
+	return tx.Recipient != "invalid"
+}
+
+func TestTxPoolNonce(t *testing.T) {
+
+	pool := newTxPool()
+	pool.SetChainState(testChain{"alice": 1})
+
+	// 1. 链上已使用的序号被拒绝
+	if err := pool.AddTx(NewTransaction("alice", "bob", 10, 1, 0)); err != ErrStaleNonce {
+		t.Errorf("stale nonce got %v", err)
+	}
+
+	// 2. 乱序加入
+	tx2 := NewTransaction("alice", "bob", 10, 1, 2)
+	tx1 := NewTransaction("alice", "bob", 10, 1, 1)
+	pool.AddTx(tx2)
+	pool.AddTx(tx1)
+
+	if err := pool.AddTx(tx1); err != ErrDuplicateTx {
+		t.Errorf("duplicate tx got %v", err)
+	}
+
+	// 3. 相同序号的冲突交易被拒绝
+	if err := pool.AddTx(NewTransaction("alice", "carol", 10, 1, 1)); err != ErrConflictTx {
+		t.Errorf("conflicting tx got %v", err)
+	}
+
+	if nonce := pool.NextNonce("alice"); nonce != 3 {
+		t.Errorf("next nonce got %d want 3", nonce)
+	}
+
+	// 4. 按序号弹出
+	txs := pool.PopTransactions(2)
+	if len(txs) != 2 || txs[0] != tx1 || txs[1] != tx2 {
+		t.Errorf("transactions not popped in nonce order")
+	}
+	if pool.Size() != 0 {
+		t.Errorf("pool should be empty, got %d", pool.Size())
+	}
+
+	// 空交易池不会panic
+	if tx := pool.GetTx(); tx != nil {
+		t.Errorf("empty pool returned a transaction")
+	}
+}
+
+func TestTxPoolPriority(t *testing.T) {
+
+	pool := newTxPool()
+
+	low := NewTransaction("alice", "bob", 10, 1, 0)
+	high := NewTransaction("bob", "alice", 10, 100, 0)
+	mid := NewTransaction("carol", "alice", 10, 50, 0)
+	for _, tx := range []*Transaction{low, high, mid} {
+		if err := pool.AddTx(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 负的手续费和非正的金额被拒绝,不能获得最高优先级
+	for _, tx := range []*Transaction{
+		NewTransaction("eve", "alice", 10, -1, 0),
+		NewTransaction("eve", "alice", 0, 1, 0),
+		NewTransaction("eve", "alice", -10, 1, 0),
+		NewTransaction("eve", "alice", MAX_MONEY+1, 1, 0),
+	} {
+		if err := pool.AddTx(tx); err != ErrAmountOutOfRange {
+			t.Errorf("value %d fee %d got %v", tx.Value, tx.Fee, err)
+		}
+	}
+
+	// 1. 按手续费率从高到低弹出
+	if tx := pool.GetTx(); tx != high {
+		t.Errorf("expected highest fee transaction first")
+	}
+
+	// 2. 超出字节上限时驱逐费率最低的交易
+	pool.SetLimits(pool.Bytes()-1, DEFAULT_MAX_TXS_PER_SENDER)
+	if pool.Has(low) || !pool.Has(mid) {
+		t.Errorf("lowest fee transaction should be evicted")
+	}
+
+	// 新交易费率过低时直接拒绝
+	if err := pool.AddTx(NewTransaction("dave", "alice", 10, 0, 0)); err != ErrPoolFull {
+		t.Errorf("low fee tx in full pool got %v", err)
+	}
+
+	// 3. 每个发送方的交易数限制
+	pool.SetLimits(DEFAULT_POOL_MAX_SIZE, 1)
+	if err := pool.AddTx(NewTransaction("carol", "alice", 10, 50, 1)); err != ErrSenderLimit {
+		t.Errorf("sender limit got %v", err)
+	}
+
+	// 4. 区块确认后移除交易
+	pool.RemoveConfirmed([]*Transaction{mid})
+	if pool.Size() != 0 || pool.Bytes() != 0 {
+		t.Errorf("confirmed transaction should be removed")
+	}
+}
+
+func TestTxPoolConcurrent(t *testing.T) {
+
+	pool := newTxPool()
+
+	const senders = 8
+	const perSender = 20
+
+	var wg sync.WaitGroup
+
+	// 1. 多个goroutine并发添加交易
+	for i := 0; i < senders; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sender := fmt.Sprintf("sender-%d", i)
+			for nonce := 0; nonce < perSender; nonce++ {
+				tx := NewTransaction(sender, "bob", 10, Amount(nonce), uint64(nonce))
+				if err := pool.AddTx(tx); err != nil {
+					t.Error(err)
+				}
+			}
+		}(i)
+	}
+
+	// 2. 同时弹出、移除和遍历交易
+	popped := make(chan *Transaction, senders*perSender*2)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tmpSender := fmt.Sprintf("tmp-%d", i)
+			for j := 0; j < perSender; j++ {
+				for _, tx := range pool.PopTransactions(1) {
+					popped <- tx
+				}
+
+				// 添加后立即移除的临时交易
+				tmp := NewTransaction(tmpSender, "bob", 10, 1, uint64(j))
+				pool.AddTx(tmp)
+				pool.RemoveTransactions([]*Transaction{tmp})
+
+				pool.Range(func(tx *Transaction) bool {
+					return pool.Has(tx)
+				})
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	close(popped)
+
+	// 3. 交易不会丢失也不会重复
+	seen := make(map[string]bool)
+	record := func(tx *Transaction) {
+		if strings.HasPrefix(tx.Sender, "tmp-") {
+			return
+		}
+		if seen[tx.IDHex()] {
+			t.Errorf("transaction seen twice")
+		}
+		seen[tx.IDHex()] = true
+	}
+	for tx := range popped {
+		record(tx)
+	}
+	for _, tx := range pool.GetTxs() {
+		record(tx)
+	}
+	if len(seen) != senders*perSender {
+		t.Errorf("expected %d transactions, got %d", senders*perSender, len(seen))
+	}
+}
+
+func TestTxPoolPersist(t *testing.T) {
+
+	privKey, pubKey := utils.GenerateKeyPair()
+	sender := utils.PubKeyToAddr(pubKey)
+	newTx := func(nonce uint64) *Transaction {
+		tx := NewTransaction(sender, "bob", 10, 1, nonce)
+		tx.Sign(privKey)
+		return tx
+	}
+
+	// 1. 保存4笔交易,其中一笔已过期,一笔不符合链上规则
+	pool := newTxPool()
+	confirmed, pending, expired := newTx(0), newTx(1), newTx(2)
+	pool.AddTx(confirmed)
+	pool.AddTx(pending)
+	pool.addTxAt(expired, time.Now().Add(-MEMPOOL_EXPIRY-time.Hour))
+	invalid := NewTransaction(sender, "invalid", 10, 1, 3)
+	invalid.Sign(privKey)
+	pool.AddTx(invalid)
+
+	path := filepath.Join(t.TempDir(), "mempool.dat")
+	if err := pool.saveTo(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// 2. 重启后序号0已在链上确认
+	restored := newTxPool()
+	restored.SetChainState(testChain{sender: 1})
+	loaded, err := restored.loadFrom(path, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded != 1 || !restored.Has(pending) {
+		t.Errorf("expected only the pending transaction to be loaded, got %d", loaded)
+	}
+
+	// 文件不存在时为空交易池
+	if loaded, err := newTxPool().loadFrom(filepath.Join(t.TempDir(), "missing.dat"), time.Now()); err != nil || loaded != 0 {
+		t.Errorf("missing file got %d, %v", loaded, err)
+	}
+
+	// 旧版本的文件被拒绝
+	raw, _ := os.ReadFile(path)
+	binary.LittleEndian.PutUint32(raw, 1)
+	os.WriteFile(path, raw, 0600)
+	if _, err := newTxPool().loadFrom(path, time.Now()); err == nil {
+		t.Errorf("old mempool file version should be rejected")
+	}
+}
+
+func TestTxPoolReplaceByFee(t *testing.T) {
+
+	pool := newTxPool()
+
+	original := NewTransaction("alice", "bob", 10, 100, 0)
+	original.Replaceable = true
+	if err := pool.AddTx(original); err != nil {
+		t.Fatal(err)
+	}
+
+	// 1. 手续费增加不足
+	cheap := NewTransaction("alice", "bob", 10, 101, 0)
+	if err := pool.AddTx(cheap); err != ErrReplacementFee {
+		t.Errorf("low fee replacement got %v", err)
+	}
+
+	// 2. 替换成功,原交易被移除
+	bumped := NewTransaction("alice", "bob", 10, 1000, 0)
+	if err := pool.AddTx(bumped); err != nil {
+		t.Fatal(err)
+	}
+	if pool.Has(original) || !pool.Has(bumped) || pool.Size() != 1 {
+		t.Errorf("original transaction should be replaced")
+	}
+
+	// 3. 未声明可替换的交易不能被替换
+	final := NewTransaction("alice", "bob", 10, 100000, 0)
+	if err := pool.AddTx(final); err != ErrConflictTx {
+		t.Errorf("replacing non-replaceable tx got %v", err)
+	}
+
+	// 4. 交易池已满时更大的替换交易被驱逐,原交易保留
+	pool = newTxPool()
+	events := pool.Subscribe(10)
+	other := NewTransaction("carol", "bob", 10, 100000, 0)
+	pool.SetLimits(len(original.Serialize())+len(other.Serialize()), DEFAULT_MAX_TXS_PER_SENDER)
+	pool.AddTx(original)
+	pool.AddTx(other)
+	for len(events) > 0 {
+		<-events
+	}
+
+	large := NewTransaction("alice", strings.Repeat("b", 200), 10, 20000, 0)
+	if err := pool.AddTx(large); err != ErrPoolFull {
+		t.Fatalf("oversized replacement got %v", err)
+	}
+	if !pool.Has(original) || !pool.Has(other) || pool.Has(large) || pool.Size() != 2 {
+		t.Errorf("pool changed by rejected replacement")
+	}
+	if len(events) != 0 {
+		t.Errorf("rejected replacement emitted %d events", len(events))
+	}
+}
+
+func TestTxPoolExpiry(t *testing.T) {
+
+	pool := newTxPool()
+	pool.SetExpiry(time.Hour)
+	events := pool.Subscribe(10)
+
+	// 1. 两小时前加入的交易及其后续序号的交易
+	old := NewTransaction("alice", "bob", 10, 1, 0)
+	child := NewTransaction("alice", "bob", 10, 1, 1)
+	fresh := NewTransaction("bob", "alice", 10, 1, 0)
+	pool.addTxAt(old, time.Now().Add(-2*time.Hour))
+	pool.AddTx(child)
+	pool.AddTx(fresh)
+
+	// 2. 过期交易和后续交易被移除
+	expired := pool.Expire(time.Now())
+	if len(expired) != 2 || pool.Size() != 1 || !pool.Has(fresh) {
+		t.Errorf("expected 2 expired transactions, got %d", len(expired))
+	}
+
+	// 3. 过期的交易不能立即重新加入
+	if err := pool.AddTx(old); err != ErrRecentlyExpired {
+		t.Errorf("re-adding expired tx got %v", err)
+	}
+
+	// 4. 过期事件
+	count := 0
+	for len(events) > 0 {
+		if event := <-events; event.Type == EventExpired {
+			count++
+		}
+	}
+	if count != 2 {
+		t.Errorf("expected 2 expired events, got %d", count)
+	}
+}
@@ -0,0 +1,54 @@
+package transaction
+
+import (
+	"crypto"
+	"errors"
+
+	"github.com/Alan-333333/simple-blockchain/utils"
+)
+
+var ErrSignerAddress = errors.New("transaction sender does not match signer address")
+
+// Signer 交易签名者,私钥可以在本进程内,也可以在独立的签名进程中
+type Signer interface {
+	// Address 签名私钥对应的地址
+	Address() string
+
+	// SignTx 签名交易,设置交易的公钥和签名
+	SignTx(tx *Transaction) error
+}
+
+// LocalSigner 使用本进程内的私钥签名
+type LocalSigner struct {
+	key     crypto.Signer
+	address string
+}
+
+// NewLocalSigner 创建使用私钥签名的签名者
+func NewLocalSigner(key crypto.Signer) (*LocalSigner, error) {
+
+	address := utils.PubKeyToAddr(key.Public())
+	if address == "" {
+		return nil, utils.ErrUnsupportedKeyType
+	}
+
+	return &LocalSigner{key: key, address: address}, nil
+}
+
+// Address 私钥对应的地址
+func (s *LocalSigner) Address() string {
+	return s.address
+}
+
+// PublicKey 私钥对应的公钥
+func (s *LocalSigner) PublicKey() crypto.PublicKey {
+	return s.key.Public()
+}
+
+// SignTx 签名交易,只签名发送方为该地址的交易
+func (s *LocalSigner) SignTx(tx *Transaction) error {
+	if tx.Sender != s.address {
+		return ErrSignerAddress
+	}
+	return tx.Sign(s.key)
+}
@@ -1,43 +1,58 @@
 package transaction
 
 import (
-	"crypto/ecdsa"
-	"crypto/rand"
-	"crypto/sha256"
-	"encoding/asn1"
-	"encoding/base64"
-	"fmt"
-	"math/big"
+	"crypto"
 
 	"github.com/Alan-333333/simple-blockchain/utils"
 )
 
 type Transaction struct {
+	Version   uint32
 	Sender    string
+	PubKey    []byte // 发送方带类型的公钥编码,地址为公钥的hash
 	Recipient string
-	Value     float32
-	Signature string
+	Value     Amount
+	Fee       Amount // 支付给矿工的手续费
+	Nonce     uint64 // 发送方的交易序号,从0开始递增
+
+	// 是否允许被相同序号、手续费更高的交易替换(RBF)
+	Replaceable bool
+	Signature   []byte // ECDSA为DER编码的签名,Ed25519为64字节签名
 	// 其他字段
 }
 
 // NewTransaction 创建新交易
-func NewTransaction(sender string, recipient string, value float32) *Transaction {
+func NewTransaction(sender string, recipient string, value Amount, fee Amount, nonce uint64) *Transaction {
 
 	tx := &Transaction{
+		Version:   CURRENT_TX_VERSION,
 		Sender:    sender,
 		Recipient: recipient,
 		Value:     value,
+		Fee:       fee,
+		Nonce:     nonce,
 	}
 
 	return tx
 
 }
 
-// Sign 交易签名
-func (tx *Transaction) Sign(privateKey *ecdsa.PrivateKey) error {
+// Sign 交易签名,支持P-256、secp256k1和Ed25519私钥
+// 签名是确定性的,相同的交易和私钥总是得到相同的txid
+func (tx *Transaction) Sign(privateKey crypto.Signer) error {
+
+	// 附加带类型的公钥,签名覆盖公钥
+	pubKey, err := utils.EncodePubKey(privateKey.Public())
+	if err != nil {
+		return err
+	}
+	tx.PubKey = pubKey
 
-	// 签名算法
-	signature := signECDSA(privateKey, tx) // 使用私钥签名
+	// 使用私钥签名交易的签名hash
+	signature, err := utils.SignHash(privateKey, tx.SigHash())
+	if err != nil {
+		return err
+	}
 
 	tx.Signature = signature // 添加签名到交易
 
@@ -46,62 +61,19 @@ func (tx *Transaction) Sign(privateKey *ecdsa.PrivateKey) error {
 
 // IsValid 验证交易签名
 func (tx *Transaction) IsValid() bool {
-	// 解析公钥
-	pubKey, err := utils.AddrToPubKey(tx.Sender)
-	if err != nil {
+	// 校验版本
+	if tx.Version != CURRENT_TX_VERSION {
 		return false
 	}
-	// 签名验证算法
-	return verifyECDSA(pubKey, tx, tx.Signature) // 使用公钥验证
-}
-
-// 对交易签名
-func signECDSA(privateKey *ecdsa.PrivateKey, tx *Transaction) string {
-	// 序列化交易
-	txBytes := serialize(tx)
-	// 计算哈希
-	txHash := sha256.Sum256(txBytes)
-
-	// 签名
-	r, s, err := ecdsa.Sign(rand.Reader, privateKey, txHash[:])
-	if err != nil {
-		return ""
-	}
-	// 序列化签名为字节数组
-	sigBytes, err := asn1.Marshal(struct{ R, S *big.Int }{r, s})
-	if err != nil {
-		return ""
-	}
-
-	// 编码为Base64
-	return base64.StdEncoding.EncodeToString(sigBytes)
-}
-
-// 验证签名
-func verifyECDSA(publicKey *ecdsa.PublicKey, tx *Transaction, sigStr string) bool {
-	// 1. Base64解码
-	sigBytes, err := base64.StdEncoding.DecodeString(sigStr)
+	// 解析公钥,类型由编码的第一个字节决定
+	pubKey, err := utils.DecodePubKey(tx.PubKey)
 	if err != nil {
 		return false
 	}
-
-	// 2. ASN.1反序列化
-	var sig struct{ R, S *big.Int }
-	if _, err := asn1.Unmarshal(sigBytes, &sig); err != nil {
+	// 公钥必须对应发送方地址,地址版本号与密钥类型一致
+	if utils.PubKeyToAddr(pubKey) != tx.Sender {
 		return false
 	}
-
-	// 3. 序列化交易
-	txBytes := serialize(tx)
-	// 4. 计算哈希
-	txHash := sha256.Sum256(txBytes)
-
-	// 5. 验证签名
-	return ecdsa.Verify(publicKey, txHash[:], sig.R, sig.S)
-}
-
-// 序列化交易
-func serialize(tx *Transaction) []byte {
-
-	return []byte(fmt.Sprintf("%s%s%d", tx.Sender, tx.Recipient, tx.Value))
+	// 按密钥类型验证签名
+	return utils.VerifySignature(pubKey, tx.SigHash(), tx.Signature)
 }
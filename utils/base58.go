@@ -7,6 +7,7 @@ import (
 
 var b58Alphabet = []byte("123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz")
 
+// Base58Encode Base58编码,每个前导0字节编码为一个'1'
 func Base58Encode(input []byte) []byte {
 	var result []byte
 	x := big.NewInt(0).SetBytes(input)
@@ -17,31 +18,42 @@ func Base58Encode(input []byte) []byte {
 		x.DivMod(x, base, mod)
 		result = append(result, b58Alphabet[mod.Int64()])
 	}
-	ReverseBytes(result)
-	for b := range result {
-		if b == 0x00 {
-			result = append([]byte{b58Alphabet[0]}, result...)
-		} else {
+
+	// 前导0字节
+	for _, b := range input {
+		if b != 0x00 {
 			break
 		}
+		result = append(result, b58Alphabet[0])
 	}
+	ReverseBytes(result)
+
 	return result
 }
 
+// Base58Decode Base58解码,包含非法字符时返回nil
 func Base58Decode(input []byte) []byte {
 	result := big.NewInt(0)
+
+	// 前导'1'对应0字节
 	zeroBytes := 0
-	for b := range input {
-		if b == 0x00 {
-			zeroBytes++
+	for _, b := range input {
+		if b != b58Alphabet[0] {
+			break
 		}
+		zeroBytes++
 	}
+
 	payload := input[zeroBytes:]
 	for _, b := range payload {
 		charIndex := bytes.IndexByte(b58Alphabet, b)
+		if charIndex < 0 {
+			return nil
+		}
 		result.Mul(result, big.NewInt(58))
 		result.Add(result, big.NewInt(int64(charIndex)))
 	}
+
 	decoded := result.Bytes()
 	decoded = append(bytes.Repeat([]byte{byte(0x00)}, zeroBytes), decoded...)
 	return decoded
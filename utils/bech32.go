@@ -0,0 +1,199 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 各网络Bech32地址的前缀(HRP)
+const (
+	BECH32_HRP_MAINNET = "sb"
+	BECH32_HRP_TESTNET = "tsb"
+)
+
+// 当前网络的Bech32前缀
+var NetworkHRP = BECH32_HRP_MAINNET
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// Bech32字符串的最大长度(BIP173)
+const bech32MaxLen = 90
+
+var bech32Generator = [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+var (
+	ErrBech32MixedCase = errors.New("bech32 string has mixed case")
+	ErrBech32Length    = errors.New("invalid bech32 string length")
+	ErrBech32Separator = errors.New("invalid bech32 separator position")
+)
+
+// ErrBech32Checksum 校验和错误
+type ErrBech32Checksum struct {
+	Expected string
+	Actual   string
+}
+
+func (e ErrBech32Checksum) Error() string {
+	return fmt.Sprintf("invalid bech32 checksum (expected %v got %v)", e.Expected, e.Actual)
+}
+
+// ErrBech32Char 非法字符
+type ErrBech32Char struct {
+	Char rune
+	Pos  int
+}
+
+func (e ErrBech32Char) Error() string {
+	return fmt.Sprintf("invalid bech32 character %q at position %d", e.Char, e.Pos)
+}
+
+// ErrBech32HRP 地址前缀与当前网络不匹配
+type ErrBech32HRP struct {
+	Expected string
+	Actual   string
+}
+
+func (e ErrBech32HRP) Error() string {
+	return fmt.Sprintf("address is for network %q, expected %q", e.Actual, e.Expected)
+}
+
+// Bech32Encode 编码Bech32字符串,data为5位分组
+func Bech32Encode(hrp string, data []byte) (string, error) {
+
+	if len(hrp)+1+len(data)+6 > bech32MaxLen || len(hrp) == 0 {
+		return "", ErrBech32Length
+	}
+	hrp = strings.ToLower(hrp)
+
+	checksum := bech32Checksum(hrp, data)
+
+	var sb strings.Builder
+	sb.WriteString(hrp)
+	sb.WriteByte('1')
+	for _, b := range append(append([]byte{}, data...), checksum...) {
+		if b > 31 {
+			return "", errors.New("bech32 data is not 5-bit")
+		}
+		sb.WriteByte(bech32Charset[b])
+	}
+
+	return sb.String(), nil
+}
+
+// Bech32Decode 解码Bech32字符串,返回前缀和5位分组的数据
+func Bech32Decode(s string) (string, []byte, error) {
+
+	// 1. 长度和大小写
+	if len(s) < 8 || len(s) > bech32MaxLen {
+		return "", nil, ErrBech32Length
+	}
+	lower := strings.ToLower(s)
+	if lower != s && strings.ToUpper(s) != s {
+		return "", nil, ErrBech32MixedCase
+	}
+	s = lower
+
+	// 2. 最后一个'1'为分隔符
+	sep := strings.LastIndexByte(s, '1')
+	if sep < 1 || sep+7 > len(s) {
+		return "", nil, ErrBech32Separator
+	}
+	hrp := s[:sep]
+	for i, c := range hrp {
+		if c < 33 || c > 126 {
+			return "", nil, ErrBech32Char{Char: c, Pos: i}
+		}
+	}
+
+	// 3. 数据部分
+	data := make([]byte, 0, len(s)-sep-1)
+	for i, c := range s[sep+1:] {
+		idx := strings.IndexRune(bech32Charset, c)
+		if idx < 0 {
+			return "", nil, ErrBech32Char{Char: c, Pos: sep + 1 + i}
+		}
+		data = append(data, byte(idx))
+	}
+
+	// 4. 校验和
+	if bech32Polymod(append(bech32HRPExpand(hrp), data...)) != 1 {
+		expected := bech32Checksum(hrp, data[:len(data)-6])
+		var sb strings.Builder
+		for _, b := range expected {
+			sb.WriteByte(bech32Charset[b])
+		}
+		return "", nil, ErrBech32Checksum{Expected: sb.String(), Actual: s[len(s)-6:]}
+	}
+
+	return hrp, data[:len(data)-6], nil
+}
+
+// ConvertBits 在不同位宽的分组之间转换,如8位字节与5位分组
+func ConvertBits(data []byte, fromBits, toBits uint, pad bool) ([]byte, error) {
+
+	var acc uint32
+	var bits uint
+	maxv := uint32(1)<<toBits - 1
+
+	var result []byte
+	for _, b := range data {
+		if uint32(b)>>fromBits != 0 {
+			return nil, errors.New("invalid data range")
+		}
+		acc = acc<<fromBits | uint32(b)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			result = append(result, byte(acc>>bits&maxv))
+		}
+	}
+
+	if pad {
+		if bits > 0 {
+			result = append(result, byte(acc<<(toBits-bits)&maxv))
+		}
+	} else if bits >= fromBits || acc<<(toBits-bits)&maxv != 0 {
+		return nil, errors.New("invalid padding")
+	}
+
+	return result, nil
+}
+
+func bech32Polymod(values []byte) uint32 {
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= bech32Generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32HRPExpand(hrp string) []byte {
+	result := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		result = append(result, hrp[i]>>5)
+	}
+	result = append(result, 0)
+	for i := 0; i < len(hrp); i++ {
+		result = append(result, hrp[i]&31)
+	}
+	return result
+}
+
+func bech32Checksum(hrp string, data []byte) []byte {
+	values := append(bech32HRPExpand(hrp), data...)
+	values = append(values, 0, 0, 0, 0, 0, 0)
+	polymod := bech32Polymod(values) ^ 1
+
+	checksum := make([]byte, 6)
+	for i := range checksum {
+		checksum[i] = byte(polymod>>uint(5*(5-i))) & 31
+	}
+	return checksum
+}
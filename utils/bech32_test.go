@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBech32(t *testing.T) {
+
+	// 1. BIP173中的合法字符串
+	valid := []string{
+		"A12UEL5L",
+		"a12uel5l",
+		"an83characterlonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1tt5tgs",
+		"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw",
+		"11qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqc8247j",
+		"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w",
+	}
+	for _, s := range valid {
+		hrp, data, err := Bech32Decode(s)
+		if err != nil {
+			t.Errorf("%s: %v", s, err)
+			continue
+		}
+		encoded, err := Bech32Encode(hrp, data)
+		if err != nil || encoded != string(bytes.ToLower([]byte(s))) {
+			t.Errorf("%s re-encoded to %s", s, encoded)
+		}
+	}
+
+	// 2. 非法字符串返回对应的错误类型
+	if _, _, err := Bech32Decode("split1checkupstagehandshakeupstreamerranterredcaperred2y9e2w"); err == nil {
+		t.Errorf("bad checksum accepted")
+	} else if _, ok := err.(ErrBech32Checksum); !ok {
+		t.Errorf("bad checksum got %T", err)
+	}
+	if _, _, err := Bech32Decode("a12UEL5L"); err != ErrBech32MixedCase {
+		t.Errorf("mixed case got %v", err)
+	}
+	if _, _, err := Bech32Decode("x1b4n0q5v"); err == nil {
+		t.Errorf("invalid character accepted")
+	} else if _, ok := err.(ErrBech32Char); !ok {
+		t.Errorf("invalid character got %T", err)
+	}
+	if _, _, err := Bech32Decode("pzry9x0s0muk"); err != ErrBech32Separator {
+		t.Errorf("missing separator got %v", err)
+	}
+}
+
+func TestAddress(t *testing.T) {
+
+	_, pubKey := GenerateKeyPair()
+	address := PubKeyToAddr(pubKey)
+
+	// 1. 地址是公钥hash
+	pubKeyHash, err := AddrToPubKeyHash(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pubKeyHash, HashPubKey(MarshalPubKey(pubKey))) {
+		t.Errorf("address does not commit to public key hash")
+	}
+
+	// 2. Bech32地址与Base58地址对应同一个公钥hash
+	bech32Addr, err := PubKeyToBech32Addr(pubKey, NetworkHRP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if normalized, err := NormalizeAddress(bech32Addr); err != nil || normalized != address {
+		t.Errorf("bech32 address normalized to %s, %v", normalized, err)
+	}
+
+	// 3. 其他网络的地址
+	testnetAddr, _ := PubKeyToBech32Addr(pubKey, BECH32_HRP_TESTNET)
+	if _, err := AddrToPubKeyHash(testnetAddr); err == nil {
+		t.Errorf("testnet address accepted")
+	} else if _, ok := err.(ErrBech32HRP); !ok {
+		t.Errorf("testnet address got %T", err)
+	}
+
+	// 4. 校验和错误
+	corrupted := []byte(bech32Addr)
+	if corrupted[len(corrupted)-1] == 'q' {
+		corrupted[len(corrupted)-1] = 'p'
+	} else {
+		corrupted[len(corrupted)-1] = 'q'
+	}
+	if _, err := AddrToPubKeyHash(string(corrupted)); err == nil {
+		t.Errorf("corrupted address accepted")
+	} else if _, ok := err.(ErrBech32Checksum); !ok {
+		t.Errorf("corrupted address got %T", err)
+	}
+	base58 := []byte(address)
+	if base58[10] == '2' {
+		base58[10] = '3'
+	} else {
+		base58[10] = '2'
+	}
+	if err := ValidateAddress(string(base58)); err == nil {
+		t.Errorf("corrupted base58 address accepted")
+	}
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"bytes"
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
@@ -11,14 +12,14 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"math/big"
+	"strings"
 
 	"golang.org/x/crypto/ripemd160"
 )
 
-const version = byte(0x00) //版本号
 const addressChecksumLen = 4
 
+// GenerateKeyPair 生成P-256密钥对,其他类型的密钥使用GenerateKey
 func GenerateKeyPair() (*ecdsa.PrivateKey, *ecdsa.PublicKey) {
 
 	// 1. 生成Curve参数
@@ -77,56 +78,142 @@ func ParsePubKey(pubKeyPEM string) (*ecdsa.PublicKey, error) {
 	return pubKey, nil
 }
 
-// PubKeyToAddr 将公钥转换为地址
-func PubKeyToAddr(pubKey *ecdsa.PublicKey) string {
+// PubKeyToAddr 将公钥转换为地址: Base58(版本号 || RIPEMD160(SHA256(公钥编码)) || 校验和)
+// 版本号由密钥类型决定,不支持的公钥返回空字符串
+func PubKeyToAddr(pubKey crypto.PublicKey) string {
 
-	// 1. 序列化公钥
-	pubKeyBytes := append(pubKey.X.Bytes(), pubKey.Y.Bytes()...)
+	// 1. 密钥类型
+	t, err := KeyTypeOf(pubKey)
+	if err != nil {
+		return ""
+	}
+
+	// 2. 序列化公钥并双哈希
+	ripmd160 := HashPubKey(MarshalPubKey(pubKey))
 
-	// 2. TODO:双哈希
-	// ripmd160 := HashPubKey(pubKeyBytes)
+	return PubKeyHashToAddr(t, ripmd160)
+}
+
+// PubKeyHashToAddr 将公钥hash编码为Base58地址
+func PubKeyHashToAddr(t KeyType, pubKeyHash []byte) string {
 
-	// 3. 构造版本号和校验和
-	// payload := append([]byte{version}, ripmd160...)
-	payload := append([]byte{version}, pubKeyBytes...)
+	// 1. 构造版本号和校验和
+	payload := append([]byte{t.AddressVersion()}, pubKeyHash...)
 	checksum := Checksum(payload)
-	// 4. 拼接完整数据
+
+	// 2. 拼接完整数据
 	fullPayload := append(payload, checksum...)
-	// 5. Base58编码
-	addressByte := Base58Encode(fullPayload)
 
-	// 6. addressByte To string
-	address := string(addressByte)
+	// 3. Base58编码
+	return string(Base58Encode(fullPayload))
+}
+
+// PubKeyToBech32Addr 将公钥转换为Bech32地址,hrp为网络前缀
+func PubKeyToBech32Addr(pubKey crypto.PublicKey, hrp string) (string, error) {
 
-	return address
+	t, err := KeyTypeOf(pubKey)
+	if err != nil {
+		return "", err
+	}
 
+	return PubKeyHashToBech32Addr(t, HashPubKey(MarshalPubKey(pubKey)), hrp)
 }
 
-// AddrToPubKey 将地址解码为公钥
-func AddrToPubKey(addr string) (*ecdsa.PublicKey, error) {
+// PubKeyHashToBech32Addr 将公钥hash编码为Bech32地址
+// 数据部分为5位的版本号加上公钥hash
+func PubKeyHashToBech32Addr(t KeyType, pubKeyHash []byte, hrp string) (string, error) {
 
-	// 1. Base58解码
-	pubKeyHash := Base58Decode([]byte(addr))
-	// 2. 分离校验和
-	checksum := pubKeyHash[len(pubKeyHash)-addressChecksumLen:]
-	payload := pubKeyHash[:len(pubKeyHash)-addressChecksumLen]
+	data, err := ConvertBits(pubKeyHash, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
 
-	// 3. 验证校验和
+	return Bech32Encode(hrp, append([]byte{t.AddressVersion()}, data...))
+}
+
+// AddrToPubKeyHash 解码地址得到公钥hash,支持Base58地址和当前网络的Bech32地址
+func AddrToPubKeyHash(addr string) ([]byte, error) {
+	_, pubKeyHash, err := DecodeAddress(addr)
+	return pubKeyHash, err
+}
+
+// DecodeAddress 解码地址得到密钥类型和公钥hash
+func DecodeAddress(addr string) (KeyType, []byte, error) {
+
+	// 1. Bech32地址
+	if strings.HasPrefix(strings.ToLower(addr), NetworkHRP+"1") {
+		return decodeBech32Addr(addr)
+	}
+	if hrp, _, err := Bech32Decode(addr); err == nil {
+		return 0, nil, ErrBech32HRP{Expected: NetworkHRP, Actual: hrp}
+	}
+
+	// 2. Base58解码
+	decoded := Base58Decode([]byte(addr))
+	if len(decoded) != 1+ripemd160.Size+addressChecksumLen {
+		return 0, nil, errors.New("invalid address length")
+	}
+
+	// 3. 分离并验证校验和
+	checksum := decoded[len(decoded)-addressChecksumLen:]
+	payload := decoded[:len(decoded)-addressChecksumLen]
 	if !ValidateChecksum(payload, checksum) {
-		return nil, errors.New("invalid checksum")
+		return 0, nil, errors.New("invalid checksum")
 	}
-	// 4. 构造公钥
-	pubKey := payload[1:] // 去掉版本号
 
-	x := pubKey[:32]
-	y := pubKey[32:]
+	// 4. 版本号决定密钥类型
+	t, err := keyTypeForVersion(payload[0])
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return t, payload[1:], nil
+}
+
+// 解码Bech32地址
+func decodeBech32Addr(addr string) (KeyType, []byte, error) {
+
+	hrp, data, err := Bech32Decode(addr)
+	if err != nil {
+		return 0, nil, err
+	}
+	if hrp != NetworkHRP {
+		return 0, nil, ErrBech32HRP{Expected: NetworkHRP, Actual: hrp}
+	}
+	if len(data) == 0 {
+		return 0, nil, errors.New("invalid address length")
+	}
+	t, err := keyTypeForVersion(data[0])
+	if err != nil {
+		return 0, nil, err
+	}
 
-	return &ecdsa.PublicKey{
-		Curve: elliptic.P256(),
-		X:     new(big.Int).SetBytes(x),
-		Y:     new(big.Int).SetBytes(y),
-	}, nil
+	pubKeyHash, err := ConvertBits(data[1:], 5, 8, false)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(pubKeyHash) != ripemd160.Size {
+		return 0, nil, errors.New("invalid address length")
+	}
+
+	return t, pubKeyHash, nil
+}
+
+// ValidateAddress 校验地址格式和校验和
+func ValidateAddress(addr string) error {
+	_, _, err := DecodeAddress(addr)
+	return err
+}
+
+// NormalizeAddress 将地址转换为规范的Base58格式,链上交易只使用规范格式
+func NormalizeAddress(addr string) (string, error) {
+
+	t, pubKeyHash, err := DecodeAddress(addr)
+	if err != nil {
+		return "", err
+	}
 
+	return PubKeyHashToAddr(t, pubKeyHash), nil
 }
 
 func HashPubKey(pubKey []byte) []byte {
@@ -0,0 +1,262 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// KeyType 密钥类型
+type KeyType byte
+
+const (
+	KeyTypeP256 KeyType = iota
+	KeyTypeSecp256k1
+	KeyTypeEd25519
+)
+
+// 默认密钥类型
+const DEFAULT_KEY_TYPE = KeyTypeP256
+
+// 各密钥类型的地址版本号
+const (
+	ADDR_VERSION_P256      = byte(0x00)
+	ADDR_VERSION_SECP256K1 = byte(0x01)
+	ADDR_VERSION_ED25519   = byte(0x02)
+)
+
+// 私钥编码中私钥标量或种子的长度
+const privateKeyLen = 32
+
+var ErrUnsupportedKeyType = errors.New("unsupported key type")
+
+func (t KeyType) String() string {
+	switch t {
+	case KeyTypeP256:
+		return "p256"
+	case KeyTypeSecp256k1:
+		return "secp256k1"
+	case KeyTypeEd25519:
+		return "ed25519"
+	}
+	return fmt.Sprintf("KeyType(%d)", byte(t))
+}
+
+// ParseKeyType 根据名称解析密钥类型
+func ParseKeyType(name string) (KeyType, error) {
+	switch strings.ToLower(name) {
+	case "p256", "p-256", "secp256r1":
+		return KeyTypeP256, nil
+	case "secp256k1":
+		return KeyTypeSecp256k1, nil
+	case "ed25519":
+		return KeyTypeEd25519, nil
+	}
+	return 0, fmt.Errorf("unknown key type %q", name)
+}
+
+// AddressVersion 密钥类型对应的地址版本号
+func (t KeyType) AddressVersion() byte {
+	switch t {
+	case KeyTypeSecp256k1:
+		return ADDR_VERSION_SECP256K1
+	case KeyTypeEd25519:
+		return ADDR_VERSION_ED25519
+	}
+	return ADDR_VERSION_P256
+}
+
+// 根据地址版本号得到密钥类型
+func keyTypeForVersion(version byte) (KeyType, error) {
+	switch version {
+	case ADDR_VERSION_P256:
+		return KeyTypeP256, nil
+	case ADDR_VERSION_SECP256K1:
+		return KeyTypeSecp256k1, nil
+	case ADDR_VERSION_ED25519:
+		return KeyTypeEd25519, nil
+	}
+	return 0, errors.New("unknown address version")
+}
+
+// KeyTypeOf 判断公钥的类型
+func KeyTypeOf(pub crypto.PublicKey) (KeyType, error) {
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return KeyTypeP256, nil
+		case S256():
+			return KeyTypeSecp256k1, nil
+		}
+	case ed25519.PublicKey:
+		if len(key) == ed25519.PublicKeySize {
+			return KeyTypeEd25519, nil
+		}
+	}
+	return 0, ErrUnsupportedKeyType
+}
+
+// GenerateKey 生成指定类型的私钥
+func GenerateKey(t KeyType) (crypto.Signer, error) {
+	switch t {
+	case KeyTypeP256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyTypeSecp256k1:
+		return generateS256Key()
+	case KeyTypeEd25519:
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		return priv, err
+	}
+	return nil, ErrUnsupportedKeyType
+}
+
+// MarshalPubKey 公钥的编码,ECDSA公钥为压缩编码,Ed25519公钥为32字节原始编码
+// 地址为该编码的hash,不包含密钥类型,类型由地址版本号表示
+func MarshalPubKey(pub crypto.PublicKey) []byte {
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		if _, err := KeyTypeOf(key); err != nil {
+			return nil
+		}
+		return elliptic.MarshalCompressed(key.Curve, key.X, key.Y)
+	case ed25519.PublicKey:
+		return append([]byte{}, key...)
+	}
+	return nil
+}
+
+// UnmarshalPubKey 解析指定类型的公钥编码
+func UnmarshalPubKey(t KeyType, data []byte) (crypto.PublicKey, error) {
+	switch t {
+	case KeyTypeP256:
+		curve := elliptic.P256()
+		x, y := elliptic.UnmarshalCompressed(curve, data)
+		if x == nil {
+			return nil, errors.New("invalid public key")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case KeyTypeSecp256k1:
+		return unmarshalCompressedS256(data)
+	case KeyTypeEd25519:
+		if len(data) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid public key")
+		}
+		return ed25519.PublicKey(append([]byte{}, data...)), nil
+	}
+	return nil, ErrUnsupportedKeyType
+}
+
+// EncodePubKey 带类型的公钥编码: 类型 || 公钥编码,用于交易和钱包文件
+func EncodePubKey(pub crypto.PublicKey) ([]byte, error) {
+
+	t, err := KeyTypeOf(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte{byte(t)}, MarshalPubKey(pub)...), nil
+}
+
+// DecodePubKey 解析带类型的公钥编码
+func DecodePubKey(data []byte) (crypto.PublicKey, error) {
+	if len(data) == 0 {
+		return nil, errors.New("invalid public key")
+	}
+	return UnmarshalPubKey(KeyType(data[0]), data[1:])
+}
+
+// MarshalPrivateKey 带类型的私钥编码: 类型 || 32字节私钥,Ed25519为种子
+func MarshalPrivateKey(key crypto.Signer) ([]byte, error) {
+
+	t, err := KeyTypeOf(key.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	data := []byte{byte(t)}
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		return append(data, k.D.FillBytes(make([]byte, privateKeyLen))...), nil
+	case ed25519.PrivateKey:
+		return append(data, k.Seed()...), nil
+	}
+	return nil, ErrUnsupportedKeyType
+}
+
+// UnmarshalPrivateKey 解析带类型的私钥编码
+func UnmarshalPrivateKey(data []byte) (crypto.Signer, error) {
+
+	if len(data) != 1+privateKeyLen {
+		return nil, errors.New("invalid private key length")
+	}
+	secret := data[1:]
+
+	var curve elliptic.Curve
+	switch KeyType(data[0]) {
+	case KeyTypeP256:
+		curve = elliptic.P256()
+	case KeyTypeSecp256k1:
+		curve = S256()
+	case KeyTypeEd25519:
+		return ed25519.NewKeyFromSeed(secret), nil
+	default:
+		return nil, ErrUnsupportedKeyType
+	}
+
+	// 私钥必须在[1, N-1]范围内
+	d := new(big.Int).SetBytes(secret)
+	if d.Sign() == 0 || d.Cmp(curve.Params().N) >= 0 {
+		return nil, errors.New("invalid private key")
+	}
+	if curve == S256() {
+		return s256KeyFromBytes(secret), nil
+	}
+
+	// P-256公钥使用crypto/ecdh计算,点乘是常数时间的
+	key, err := ecdh.P256().NewPrivateKey(secret)
+	if err != nil {
+		return nil, errors.New("invalid private key")
+	}
+	pub := key.PublicKey().Bytes()
+
+	priv := &ecdsa.PrivateKey{D: d}
+	priv.PublicKey.Curve = curve
+	priv.PublicKey.X = new(big.Int).SetBytes(pub[1:33])
+	priv.PublicKey.Y = new(big.Int).SetBytes(pub[33:])
+
+	return priv, nil
+}
+
+// SignHash 使用私钥签名SHA-256 hash,签名是确定性的
+// ECDSA签名使用RFC 6979随机数和低S,DER编码;Ed25519直接签名hash
+func SignHash(key crypto.Signer, hash []byte) ([]byte, error) {
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		return signECDSA(k, hash)
+	case ed25519.PrivateKey:
+		return ed25519.Sign(k, hash), nil
+	}
+	return nil, ErrUnsupportedKeyType
+}
+
+// VerifySignature 使用公钥验证hash的签名,ECDSA签名必须是严格DER编码且为低S
+func VerifySignature(pub crypto.PublicKey, hash, sig []byte) bool {
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		return verifyECDSA(key, hash, sig)
+	case ed25519.PublicKey:
+		if len(key) != ed25519.PublicKeySize {
+			return false
+		}
+		return ed25519.Verify(key, hash, sig)
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+func TestSecp256k1(t *testing.T) {
+
+	curve := S256()
+
+	// 1. 已知的倍点
+	vectors := []struct {
+		k    int64
+		x, y string
+	}{
+		{2, "c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5", "1ae168fea63dc339a3c58419466ceaeef7f632653266d0e1236431a950cfe52a"},
+		{3, "f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9", "388f7b0f632de8140fe337e62a37f3566500a99934c2231b6cb9fd7584b8e672"},
+	}
+	for _, v := range vectors {
+		x, y := curve.ScalarBaseMult(big.NewInt(v.k).Bytes())
+		if hex.EncodeToString(x.Bytes()) != v.x || hex.EncodeToString(y.Bytes()) != v.y {
+			t.Errorf("%d*G = (%x, %x)", v.k, x, y)
+		}
+		if !curve.IsOnCurve(x, y) {
+			t.Errorf("%d*G not on curve", v.k)
+		}
+	}
+
+	// 2. 加法与倍点一致
+	gx, gy := curve.Params().Gx, curve.Params().Gy
+	x2, y2 := curve.Double(gx, gy)
+	x3, y3 := curve.Add(x2, y2, gx, gy)
+	if hex.EncodeToString(x3.Bytes()) != vectors[1].x || hex.EncodeToString(y3.Bytes()) != vectors[1].y {
+		t.Errorf("2G + G != 3G")
+	}
+
+	// 3. 私钥1的压缩公钥hash与比特币一致
+	priv, err := UnmarshalPrivateKey(append([]byte{byte(KeyTypeSecp256k1)}, big.NewInt(1).FillBytes(make([]byte, 32))...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash := hex.EncodeToString(HashPubKey(MarshalPubKey(priv.Public()))); hash != "751e76e8199196d454941c45d1b3a323f1433bd6" {
+		t.Errorf("hash160 of secp256k1 generator got %s", hash)
+	}
+
+	// 4. 压缩公钥解析后与私钥的公钥一致
+	pub, err := UnmarshalPubKey(KeyTypeSecp256k1, MarshalPubKey(priv.Public()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pub.(*ecdsa.PublicKey).Equal(priv.Public()) {
+		t.Errorf("parsed secp256k1 public key mismatch")
+	}
+	if _, err := UnmarshalPubKey(KeyTypeSecp256k1, append([]byte{0x04}, MarshalPubKey(priv.Public())[1:]...)); err == nil {
+		t.Errorf("invalid compressed public key accepted")
+	}
+
+	n := curve.Params().N
+	// (n-1)*G = -G,n*G和0*G为无穷远点
+	x, y := curve.ScalarBaseMult(new(big.Int).Sub(n, big.NewInt(1)).Bytes())
+	if x.Cmp(gx) != 0 || y.Cmp(new(big.Int).Sub(curve.Params().P, gy)) != 0 {
+		t.Errorf("(n-1)*G != -G")
+	}
+	for _, k := range [][]byte{n.Bytes(), make([]byte, 32)} {
+		if x, y := curve.ScalarBaseMult(k); x.Sign() != 0 || y.Sign() != 0 {
+			t.Errorf("%x*G should be the point at infinity", k)
+		}
+	}
+}
+
+func TestKeyTypes(t *testing.T) {
+
+	hash := sha256.Sum256([]byte("message"))
+	addresses := map[string]bool{}
+
+	for _, keyType := range []KeyType{KeyTypeP256, KeyTypeSecp256k1, KeyTypeEd25519} {
+
+		key, err := GenerateKey(keyType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kt, err := KeyTypeOf(key.Public()); err != nil || kt != keyType {
+			t.Fatalf("%v key reported as %v, %v", keyType, kt, err)
+		}
+
+		// 1. 公钥编解码
+		encoded, err := EncodePubKey(key.Public())
+		if err != nil {
+			t.Fatal(err)
+		}
+		pub, err := DecodePubKey(encoded)
+		if err != nil {
+			t.Fatalf("%v: %v", keyType, err)
+		}
+		if !bytes.Equal(MarshalPubKey(pub), MarshalPubKey(key.Public())) {
+			t.Errorf("%v public key changed after decode", keyType)
+		}
+
+		// 2. 私钥编解码
+		data, err := MarshalPrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := UnmarshalPrivateKey(data)
+		if err != nil {
+			t.Fatalf("%v: %v", keyType, err)
+		}
+		if !bytes.Equal(MarshalPubKey(decoded.Public()), MarshalPubKey(key.Public())) {
+			t.Errorf("%v private key changed after decode", keyType)
+		}
+
+		// 3. 签名验证
+		sig, err := SignHash(key, hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !VerifySignature(pub, hash[:], sig) {
+			t.Errorf("%v signature not valid", keyType)
+		}
+		if VerifySignature(pub, []byte("other hash"), sig) {
+			t.Errorf("%v signature valid for other hash", keyType)
+		}
+
+		// 4. 地址版本号对应密钥类型
+		address := PubKeyToAddr(pub)
+		addrType, _, err := DecodeAddress(address)
+		if err != nil || addrType != keyType {
+			t.Errorf("%v address decoded as %v, %v", keyType, addrType, err)
+		}
+		addresses[address] = true
+	}
+
+	if len(addresses) != 3 {
+		t.Errorf("addresses not distinct")
+	}
+
+	// 5. 不同类型的公钥编码互不通用
+	p256, _ := GenerateKey(KeyTypeP256)
+	encoded, _ := EncodePubKey(p256.Public())
+	encoded[0] = byte(KeyTypeSecp256k1)
+	if pub, err := DecodePubKey(encoded); err == nil && PubKeyToAddr(pub) == PubKeyToAddr(p256.Public()) {
+		t.Errorf("key type not committed in address")
+	}
+	if _, err := KeyTypeOf(&ecdsa.PublicKey{}); err != ErrUnsupportedKeyType {
+		t.Errorf("unknown curve got %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+)
+
+// 消息签名的域分隔前缀,消息签名不能作为交易或地址公告的签名使用
+const MESSAGE_SIG_PREFIX = "simple-blockchain signed message:"
+
+var ErrMessageAddress = errors.New("message signature does not match address")
+
+// MessageHash 消息的签名hash: SHA256(SHA256(前缀 || 消息长度 || 消息))
+func MessageHash(message string) []byte {
+
+	buf := new(bytes.Buffer)
+	buf.WriteString(MESSAGE_SIG_PREFIX)
+	binary.Write(buf, binary.LittleEndian, uint32(len(message)))
+	buf.WriteString(message)
+
+	first := sha256.Sum256(buf.Bytes())
+	second := sha256.Sum256(first[:])
+
+	return second[:]
+}
+
+// SignMessage 使用私钥签名消息,返回Base64编码的签名
+// 签名中包含带类型的公钥,验证时由公钥计算地址: 公钥长度 || 公钥 || 签名
+func SignMessage(key crypto.Signer, message string) (string, error) {
+
+	pubKey, err := EncodePubKey(key.Public())
+	if err != nil {
+		return "", err
+	}
+	sig, err := SignHash(key, MessageHash(message))
+	if err != nil {
+		return "", err
+	}
+
+	data := append([]byte{byte(len(pubKey))}, pubKey...)
+	data = append(data, sig...)
+
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// VerifyMessage 验证消息签名由地址对应的私钥生成,地址支持Base58和Bech32格式
+func VerifyMessage(address, message, signature string) error {
+
+	// 1. 解码签名和公钥
+	data, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil || len(data) == 0 || len(data) < 1+int(data[0]) {
+		return ErrInvalidSignature
+	}
+	pubKey, err := DecodePubKey(data[1 : 1+data[0]])
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	// 2. 公钥必须对应地址
+	address, err = NormalizeAddress(address)
+	if err != nil {
+		return err
+	}
+	if PubKeyToAddr(pubKey) != address {
+		return ErrMessageAddress
+	}
+
+	// 3. 验证签名
+	if !VerifySignature(pubKey, MessageHash(message), data[1+data[0]:]) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+)
+
+func TestSignMessage(t *testing.T) {
+
+	for _, keyType := range []KeyType{KeyTypeP256, KeyTypeSecp256k1, KeyTypeEd25519} {
+
+		key, err := GenerateKey(keyType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		address := PubKeyToAddr(key.Public())
+		bech32, _ := PubKeyToBech32Addr(key.Public(), NetworkHRP)
+
+		// 1. 签名并按地址验证,两种地址格式都可以
+		sig, err := SignMessage(key, "withdraw to account 42")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, addr := range []string{address, bech32} {
+			if err := VerifyMessage(addr, "withdraw to account 42", sig); err != nil {
+				t.Errorf("%v verify with %s: %v", keyType, addr, err)
+			}
+		}
+
+		// 2. 其他消息或其他地址
+		if err := VerifyMessage(address, "withdraw to account 43", sig); err != ErrInvalidSignature {
+			t.Errorf("%v other message got %v", keyType, err)
+		}
+		other, _ := GenerateKey(keyType)
+		if err := VerifyMessage(PubKeyToAddr(other.Public()), "withdraw to account 42", sig); err != ErrMessageAddress {
+			t.Errorf("%v other address got %v", keyType, err)
+		}
+
+		// 3. 消息签名不是对原始hash的签名
+		data, _ := base64.StdEncoding.DecodeString(sig)
+		raw := data[1+data[0]:]
+		if VerifySignature(key.Public(), doubleHashForTest("withdraw to account 42"), raw) {
+			t.Errorf("%v message signature valid without domain prefix", keyType)
+		}
+	}
+
+	// 4. 无效的编码
+	for _, sig := range []string{"", "!!", base64.StdEncoding.EncodeToString([]byte{40, 1, 2})} {
+		if err := VerifyMessage("1JLfCguhUBui6MWQ4vNFgEktn87E9V6F8Q", "m", sig); err != ErrInvalidSignature {
+			t.Errorf("signature %q got %v", sig, err)
+		}
+	}
+}
+
+// 不带前缀的消息双SHA256
+func doubleHashForTest(message string) []byte {
+	first := sha256.Sum256([]byte(message))
+	second := sha256.Sum256(first[:])
+	return second[:]
+}
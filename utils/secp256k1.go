@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"errors"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// secp256k1曲线使用decred的实现,曲线运算、私钥和公钥解析都由该库完成
+// 密钥仍使用ecdsa.PrivateKey和ecdsa.PublicKey表示,Curve为S256()
+
+// S256 返回secp256k1曲线
+func S256() elliptic.Curve {
+	return secp256k1.S256()
+}
+
+// 生成secp256k1私钥
+func generateS256Key() (*ecdsa.PrivateKey, error) {
+	key, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	return key.ToECDSA(), nil
+}
+
+// 由32字节私钥得到secp256k1私钥,调用方检查私钥范围
+func s256KeyFromBytes(secret []byte) *ecdsa.PrivateKey {
+	return secp256k1.PrivKeyFromBytes(secret).ToECDSA()
+}
+
+// 解析secp256k1压缩公钥
+func unmarshalCompressedS256(data []byte) (*ecdsa.PublicKey, error) {
+
+	if len(data) != secp256k1.PubKeyBytesLenCompressed {
+		return nil, errors.New("invalid compressed public key")
+	}
+	key, err := secp256k1.ParsePubKey(data)
+	if err != nil {
+		return nil, errors.New("invalid compressed public key")
+	}
+
+	return key.ToECDSA(), nil
+}
@@ -0,0 +1,196 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// 秘密分享份额编码的版本号
+const SHARE_VERSION = byte(0x53)
+
+// 恢复后用于校验秘密的摘要长度
+const secretChecksumLen = 4
+
+var (
+	ErrInvalidShare     = errors.New("invalid secret share")
+	ErrShareMismatch    = errors.New("secret shares are from different splits")
+	ErrNotEnoughShares  = errors.New("not enough secret shares")
+	ErrSecretChecksum   = errors.New("recovered secret checksum mismatch")
+	ErrInvalidThreshold = errors.New("threshold must be between 2 and the number of shares, at most 255 shares")
+)
+
+// Share Shamir秘密分享的一份,Data为各字节多项式在Index处的值
+type Share struct {
+	ID        uint16 // 同一次分割的所有份额相同,避免混用
+	Threshold byte   // 恢复需要的份额数量
+	Index     byte   // 份额的x坐标,从1开始
+	Data      []byte
+}
+
+// SplitSecret 在GF(256)上将秘密分割为n份,任意m份可以恢复
+// 秘密的每个字节使用独立的m-1次随机多项式,常数项为该字节
+// 分割前在秘密后附加校验和,恢复时可以发现错误的份额组合
+func SplitSecret(secret []byte, n, m int) ([]*Share, error) {
+
+	if m < 2 || m > n || n > 255 {
+		return nil, ErrInvalidThreshold
+	}
+
+	// 1. 秘密和校验和
+	data := append(append([]byte{}, secret...), Checksum(secret)...)
+
+	var id [2]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, err
+	}
+	shares := make([]*Share, n)
+	for i := range shares {
+		shares[i] = &Share{
+			ID:        binary.BigEndian.Uint16(id[:]),
+			Threshold: byte(m),
+			Index:     byte(i + 1),
+			Data:      make([]byte, len(data)),
+		}
+	}
+
+	// 2. 每个字节生成随机多项式并在各份额的x处求值
+	coeffs := make([]byte, m)
+	for j, b := range data {
+		coeffs[0] = b
+		if _, err := rand.Read(coeffs[1:]); err != nil {
+			return nil, err
+		}
+		for _, share := range shares {
+			share.Data[j] = gfEval(coeffs, share.Index)
+		}
+	}
+
+	return shares, nil
+}
+
+// CombineShares 使用至少Threshold份不同的份额恢复秘密
+func CombineShares(shares []*Share) ([]byte, error) {
+
+	if len(shares) == 0 {
+		return nil, ErrNotEnoughShares
+	}
+
+	// 1. 份额必须来自同一次分割,x坐标不能重复
+	first := shares[0]
+	seen := make(map[byte]bool)
+	var used []*Share
+	for _, share := range shares {
+		if share.ID != first.ID || share.Threshold != first.Threshold || len(share.Data) != len(first.Data) {
+			return nil, ErrShareMismatch
+		}
+		if share.Index == 0 {
+			return nil, ErrInvalidShare
+		}
+		if seen[share.Index] {
+			continue
+		}
+		seen[share.Index] = true
+		used = append(used, share)
+	}
+	if len(used) < int(first.Threshold) {
+		return nil, ErrNotEnoughShares
+	}
+	used = used[:first.Threshold]
+
+	// 2. 拉格朗日插值求x=0处的值
+	data := make([]byte, len(first.Data))
+	for i, share := range used {
+
+		// 基多项式在0处的值: ∏ x_k / (x_k - x_i),GF(256)中减法为异或
+		basis := byte(1)
+		for k, other := range used {
+			if k == i {
+				continue
+			}
+			basis = gfMul(basis, gfMul(other.Index, gfInv(other.Index^share.Index)))
+		}
+		for j := range data {
+			data[j] ^= gfMul(share.Data[j], basis)
+		}
+	}
+
+	// 3. 校验恢复的秘密
+	if len(data) < secretChecksumLen {
+		return nil, ErrInvalidShare
+	}
+	secret := data[:len(data)-secretChecksumLen]
+	if !ValidateChecksum(secret, data[len(secret):]) {
+		return nil, ErrSecretChecksum
+	}
+
+	return secret, nil
+}
+
+// Encode 编码份额: Base58(版本号 || ID || 门限 || 序号 || 数据 || 校验和)
+func (s *Share) Encode() string {
+
+	payload := []byte{SHARE_VERSION, byte(s.ID >> 8), byte(s.ID), s.Threshold, s.Index}
+	payload = append(payload, s.Data...)
+
+	return string(Base58Encode(append(payload, Checksum(payload)...)))
+}
+
+// DecodeShare 解析编码的份额,校验和错误时返回ErrInvalidShare
+func DecodeShare(encoded string) (*Share, error) {
+
+	decoded := Base58Decode([]byte(encoded))
+	if len(decoded) < 5+secretChecksumLen+addressChecksumLen {
+		return nil, ErrInvalidShare
+	}
+
+	payload := decoded[:len(decoded)-addressChecksumLen]
+	if !ValidateChecksum(payload, decoded[len(payload):]) {
+		return nil, ErrInvalidShare
+	}
+	if payload[0] != SHARE_VERSION {
+		return nil, fmt.Errorf("unsupported share version %d", payload[0])
+	}
+	if payload[3] < 2 || payload[4] == 0 {
+		return nil, ErrInvalidShare
+	}
+
+	return &Share{
+		ID:        binary.BigEndian.Uint16(payload[1:3]),
+		Threshold: payload[3],
+		Index:     payload[4],
+		Data:      append([]byte{}, payload[5:]...),
+	}, nil
+}
+
+// 使用Horner方法在x处求多项式的值,coeffs[0]为常数项
+func gfEval(coeffs []byte, x byte) byte {
+	var y byte
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		y = gfMul(y, x) ^ coeffs[i]
+	}
+	return y
+}
+
+// GF(256)乘法,模多项式为x^8 + x^4 + x^3 + x + 1(与AES相同)
+// 不按秘密数据分支,运算时间与数值无关
+func gfMul(a, b byte) byte {
+	var p byte
+	for i := 0; i < 8; i++ {
+		p ^= -(b & 1) & a
+		a = a<<1 ^ (-(a >> 7) & 0x1b)
+		b >>= 1
+	}
+	return p
+}
+
+// GF(256)乘法逆元,a^254 = a^-1,0没有逆元返回0
+func gfInv(a byte) byte {
+	result := byte(1)
+	for i := 0; i < 7; i++ {
+		a = gfMul(a, a)
+		result = gfMul(result, a)
+	}
+	return result
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGF256(t *testing.T) {
+
+	// FIPS-197中的乘法示例
+	if got := gfMul(0x57, 0x83); got != 0xc1 {
+		t.Errorf("0x57 * 0x83 = %#x, want 0xc1", got)
+	}
+	if got := gfMul(0x57, 0x13); got != 0xfe {
+		t.Errorf("0x57 * 0x13 = %#x, want 0xfe", got)
+	}
+	for a := 1; a < 256; a++ {
+		if gfMul(byte(a), gfInv(byte(a))) != 1 {
+			t.Fatalf("%#x * inverse != 1", a)
+		}
+	}
+}
+
+func TestShamir(t *testing.T) {
+
+	secret := []byte("a wallet private key of 32 bytes")
+
+	shares, err := SplitSecret(secret, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 1. 任意3份都可以恢复,编码往返不变
+	for i := 0; i < 5; i++ {
+		for j := i + 1; j < 5; j++ {
+			for k := j + 1; k < 5; k++ {
+				var subset []*Share
+				for _, idx := range []int{k, i, j} {
+					decoded, err := DecodeShare(shares[idx].Encode())
+					if err != nil {
+						t.Fatal(err)
+					}
+					subset = append(subset, decoded)
+				}
+				got, err := CombineShares(subset)
+				if err != nil || !bytes.Equal(got, secret) {
+					t.Errorf("shares %d,%d,%d recovered %q, %v", i, j, k, got, err)
+				}
+			}
+		}
+	}
+
+	// 2. 份额不足或重复
+	if _, err := CombineShares(shares[:2]); err != ErrNotEnoughShares {
+		t.Errorf("2 shares got %v", err)
+	}
+	if _, err := CombineShares([]*Share{shares[0], shares[1], shares[1]}); err != ErrNotEnoughShares {
+		t.Errorf("duplicate shares got %v", err)
+	}
+
+	// 3. 篡改的份额恢复时发现校验和错误
+	tampered := *shares[2]
+	tampered.Data = append([]byte{}, shares[2].Data...)
+	tampered.Data[0] ^= 1
+	if _, err := CombineShares([]*Share{shares[0], shares[1], &tampered}); err != ErrSecretChecksum {
+		t.Errorf("tampered share got %v", err)
+	}
+
+	// 4. 不同分割的份额不能混用
+	others, _ := SplitSecret(secret, 5, 3)
+	others[2].ID = shares[0].ID + 1
+	if _, err := CombineShares([]*Share{shares[0], shares[1], others[2]}); err != ErrShareMismatch {
+		t.Errorf("mixed shares got %v", err)
+	}
+
+	// 5. 编码的校验和
+	encoded := []byte(shares[0].Encode())
+	encoded[len(encoded)/2] ^= 1
+	if _, err := DecodeShare(string(encoded)); err != ErrInvalidShare {
+		t.Errorf("corrupted share got %v", err)
+	}
+
+	// 6. 门限参数
+	for _, p := range [][2]int{{3, 1}, {2, 3}, {256, 2}} {
+		if _, err := SplitSecret(secret, p[0], p[1]); err != ErrInvalidThreshold {
+			t.Errorf("%d-of-%d got %v", p[1], p[0], err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secpecdsa "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+var ErrInvalidSignature = errors.New("invalid signature encoding")
+
+// ECDSA签名
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+// 使用RFC 6979确定性随机数签名SHA-256 hash,S规范化为低值,返回DER编码
+// 相同的私钥和hash总是得到相同的签名
+// 私钥运算都是常数时间的: secp256k1使用decred库的签名,P-256使用标准库的确定性签名
+func signECDSA(priv *ecdsa.PrivateKey, hash []byte) ([]byte, error) {
+
+	// 1. secp256k1签名已经是低S的严格DER编码
+	if priv.Curve == S256() {
+		key := secp256k1.PrivKeyFromBytes(priv.D.FillBytes(make([]byte, privateKeyLen)))
+		defer key.Zero()
+		return secpecdsa.Sign(key, hash).Serialize(), nil
+	}
+
+	// 2. P-256,random为nil时按RFC 6979生成随机数
+	sig, err := priv.Sign(nil, hash, crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. 低S规范化,(r, n-s)同样有效,只接受较小的一个
+	// 签名是公开的,这里的运算不涉及私钥
+	var parsed ecdsaSignature
+	if _, err := asn1.Unmarshal(sig, &parsed); err != nil {
+		return nil, err
+	}
+	n := priv.Curve.Params().N
+	if !isLowS(parsed.S, n) {
+		parsed.S.Sub(n, parsed.S)
+	}
+
+	return asn1.Marshal(parsed)
+}
+
+// 验证DER编码的ECDSA签名,要求编码严格且S为低值
+func verifyECDSA(pub *ecdsa.PublicKey, hash, sig []byte) bool {
+
+	r, s, err := ParseSignature(sig, pub.Curve.Params().N)
+	if err != nil {
+		return false
+	}
+
+	return ecdsa.Verify(pub, hash, r, s)
+}
+
+// ParseSignature 严格解析DER编码的ECDSA签名
+// 编码必须是最短形式且没有多余数据,r和s在[1, n-1]范围内,s不超过n/2
+func ParseSignature(sig []byte, n *big.Int) (*big.Int, *big.Int, error) {
+
+	// 1. 解析并重新编码,不一致说明不是规范的DER编码
+	var parsed ecdsaSignature
+	rest, err := asn1.Unmarshal(sig, &parsed)
+	if err != nil || len(rest) != 0 {
+		return nil, nil, ErrInvalidSignature
+	}
+	canonical, err := asn1.Marshal(parsed)
+	if err != nil || !bytes.Equal(canonical, sig) {
+		return nil, nil, ErrInvalidSignature
+	}
+
+	// 2. 范围检查
+	r, s := parsed.R, parsed.S
+	if r.Sign() <= 0 || s.Sign() <= 0 || r.Cmp(n) >= 0 || s.Cmp(n) >= 0 {
+		return nil, nil, ErrInvalidSignature
+	}
+
+	// 3. 拒绝高S签名,避免签名延展性
+	if !isLowS(s, n) {
+		return nil, nil, ErrInvalidSignature
+	}
+
+	return r, s, nil
+}
+
+// s是否不超过n/2
+func isLowS(s, n *big.Int) bool {
+	half := new(big.Int).Rsh(n, 1)
+	return s.Cmp(half) <= 0
+}
@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+func hexInt(s string) *big.Int {
+	n, _ := new(big.Int).SetString(s, 16)
+	return n
+}
+
+func ecdsaKey(t KeyType, d *big.Int) *ecdsa.PrivateKey {
+	key, _ := UnmarshalPrivateKey(append([]byte{byte(t)}, d.FillBytes(make([]byte, 32))...))
+	return key.(*ecdsa.PrivateKey)
+}
+
+func TestRFC6979(t *testing.T) {
+
+	// 1. RFC 6979 A.2.5, P-256, SHA-256, "sample"
+	priv := ecdsaKey(KeyTypeP256, hexInt("C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721"))
+	hash := sha256.Sum256([]byte("sample"))
+	n := priv.Curve.Params().N
+
+	// 公钥与向量一致
+	if priv.X.Cmp(hexInt("60FED4BA255A9D31C961EB74C6356D68C049B8923B61FA6CE669622E60F29FB6")) != 0 ||
+		priv.Y.Cmp(hexInt("7903FE1008B8BC99A41AE9E95628BC64F2F1B20C2D7E9F5177A3C294D4462299")) != 0 {
+		t.Errorf("public key got (%x, %x)", priv.X, priv.Y)
+	}
+
+	sig, err := SignHash(priv, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, s, err := ParseSignature(sig, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 向量中的s为高值,签名时规范化为n-s
+	wantS := new(big.Int).Sub(n, hexInt("F7CB1C942D657C41D436C7A1B6E29F65F3E900DBB9AFF4064DC4AB2F843ACDA8"))
+	if r.Cmp(hexInt("EFD48B2AACB6A8FD1140DD9CD45E81D69D2C877B56AAF991C34D0EA84EAF3716")) != 0 || s.Cmp(wantS) != 0 {
+		t.Errorf("signature got r=%x s=%x", r, s)
+	}
+
+	// RFC 6979 A.2.5, P-256, SHA-256, "test",s为低值
+	hash = sha256.Sum256([]byte("test"))
+	sig, err = SignHash(priv, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, s, _ = ParseSignature(sig, n)
+	if r.Cmp(hexInt("F1ABB023518351CD71D881567B1EA663ED3EFCF6C5132B354F28D3B0B7D38367")) != 0 ||
+		s.Cmp(hexInt("019F4113742A2B14BD25926B49C649155F267E60D3814B4C0CC84250E46F0083")) != 0 {
+		t.Errorf("signature got r=%x s=%x", r, s)
+	}
+
+	// 2. secp256k1,私钥1,"Satoshi Nakamoto"
+	priv = ecdsaKey(KeyTypeSecp256k1, big.NewInt(1))
+	hash = sha256.Sum256([]byte("Satoshi Nakamoto"))
+	sig, err = SignHash(priv, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "3045022100934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d802202442ce9d2b916064108014783e923ec36b49743e2ffa1c4496f01a512aafd9e5"
+	if hex.EncodeToString(sig) != want {
+		t.Errorf("secp256k1 signature got %x", sig)
+	}
+	if !VerifySignature(&priv.PublicKey, hash[:], sig) {
+		t.Errorf("secp256k1 signature not valid")
+	}
+
+	// 3. 签名是确定性的
+	again, _ := SignHash(priv, hash[:])
+	if !bytes.Equal(sig, again) {
+		t.Errorf("signature is not deterministic")
+	}
+}
+
+func TestStrictSignature(t *testing.T) {
+
+	for _, keyType := range []KeyType{KeyTypeP256, KeyTypeSecp256k1} {
+
+		key, _ := GenerateKey(keyType)
+		priv := key.(*ecdsa.PrivateKey)
+		n := priv.Curve.Params().N
+		hash := sha256.Sum256([]byte("message"))
+
+		sig, _ := SignHash(priv, hash[:])
+		r, s, err := ParseSignature(sig, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !VerifySignature(&priv.PublicKey, hash[:], sig) {
+			t.Fatalf("%v signature not valid", keyType)
+		}
+
+		// 1. 高S签名数学上有效但被拒绝
+		highS, _ := asn1.Marshal(ecdsaSignature{r, new(big.Int).Sub(n, s)})
+		if !ecdsa.Verify(&priv.PublicKey, hash[:], r, new(big.Int).Sub(n, s)) {
+			t.Fatalf("%v high-S signature should be mathematically valid", keyType)
+		}
+		if VerifySignature(&priv.PublicKey, hash[:], highS) {
+			t.Errorf("%v high-S signature accepted", keyType)
+		}
+
+		// 2. 多余的数据
+		if VerifySignature(&priv.PublicKey, hash[:], append(append([]byte{}, sig...), 0)) {
+			t.Errorf("%v signature with trailing bytes accepted", keyType)
+		}
+
+		// 3. 非最短的长度编码
+		long := append([]byte{0x30, 0x81, sig[1]}, sig[2:]...)
+		if VerifySignature(&priv.PublicKey, hash[:], long) {
+			t.Errorf("%v non-canonical length accepted", keyType)
+		}
+
+		// 4. 整数带多余的前导零
+		rBytes := r.Bytes()
+		padded := []byte{0x02, byte(len(rBytes) + 2), 0x00, 0x00}
+		padded = append(padded, rBytes...)
+		sPart := sig[2+2+int(sig[3]):]
+		body := append(padded, sPart...)
+		padded = append([]byte{0x30, byte(len(body))}, body...)
+		if VerifySignature(&priv.PublicKey, hash[:], padded) {
+			t.Errorf("%v padded integer accepted", keyType)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"crypto"
+	"errors"
+)
+
+// WIF版本号,secp256k1使用比特币主网的0x80,与比特币钱包兼容
+const (
+	WIF_VERSION_SECP256K1 = byte(0x80)
+	WIF_VERSION_P256      = byte(0x81)
+	WIF_VERSION_ED25519   = byte(0x82)
+)
+
+// WIF私钥后缀,表示对应的公钥使用压缩编码
+const wifCompressed = byte(0x01)
+
+var ErrInvalidWIF = errors.New("invalid WIF private key")
+
+// EncodeWIF 将私钥编码为WIF格式: Base58(版本号 || 32字节私钥 || 0x01 || 校验和)
+// 版本号由密钥类型决定,Ed25519编码种子
+func EncodeWIF(key crypto.Signer) (string, error) {
+
+	// 1. 带类型的私钥编码
+	data, err := MarshalPrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	t := KeyType(data[0])
+
+	// 2. 版本号和压缩标记
+	payload := append([]byte{wifVersion(t)}, data[1:]...)
+	payload = append(payload, wifCompressed)
+
+	// 3. 校验和并Base58编码
+	return string(Base58Encode(append(payload, Checksum(payload)...))), nil
+}
+
+// DecodeWIF 解析WIF格式的私钥
+func DecodeWIF(wif string) (crypto.Signer, error) {
+
+	// 1. Base58解码
+	decoded := Base58Decode([]byte(wif))
+	if len(decoded) != 1+privateKeyLen+1+addressChecksumLen {
+		return nil, ErrInvalidWIF
+	}
+
+	// 2. 验证校验和
+	payload := decoded[:len(decoded)-addressChecksumLen]
+	if !ValidateChecksum(payload, decoded[len(decoded)-addressChecksumLen:]) {
+		return nil, ErrInvalidWIF
+	}
+	if payload[len(payload)-1] != wifCompressed {
+		return nil, ErrInvalidWIF
+	}
+
+	// 3. 版本号决定密钥类型
+	var t KeyType
+	switch payload[0] {
+	case WIF_VERSION_SECP256K1:
+		t = KeyTypeSecp256k1
+	case WIF_VERSION_P256:
+		t = KeyTypeP256
+	case WIF_VERSION_ED25519:
+		t = KeyTypeEd25519
+	default:
+		return nil, ErrInvalidWIF
+	}
+
+	return UnmarshalPrivateKey(append([]byte{byte(t)}, payload[1:1+privateKeyLen]...))
+}
+
+// 密钥类型对应的WIF版本号
+func wifVersion(t KeyType) byte {
+	switch t {
+	case KeyTypeSecp256k1:
+		return WIF_VERSION_SECP256K1
+	case KeyTypeEd25519:
+		return WIF_VERSION_ED25519
+	}
+	return WIF_VERSION_P256
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+)
+
+func TestWIF(t *testing.T) {
+
+	// 1. 私钥1的secp256k1 WIF与比特币压缩WIF的编码一致
+	one := big.NewInt(1).FillBytes(make([]byte, 32))
+	key, err := UnmarshalPrivateKey(append([]byte{byte(KeyTypeSecp256k1)}, one...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wif, err := EncodeWIF(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wif != "KwDiBf89QgGbjEhKnhXJuH7LrciVrZi3qYjgd9M7rFU73sVHnoWn" {
+		t.Errorf("WIF of key 1 got %s", wif)
+	}
+
+	// 相同私钥的P-256 WIF使用不同的版本号
+	p256Key, _ := UnmarshalPrivateKey(append([]byte{byte(KeyTypeP256)}, one...))
+	p256WIF, _ := EncodeWIF(p256Key)
+	if decoded, err := DecodeWIF(p256WIF); p256WIF == wif || err != nil || PubKeyToAddr(decoded.Public()) != PubKeyToAddr(p256Key.Public()) {
+		t.Errorf("P-256 WIF of key 1 got %s, %v", p256WIF, err)
+	}
+
+	// 2. 各类型私钥往返编码,类型保持不变
+	for _, keyType := range []KeyType{KeyTypeP256, KeyTypeSecp256k1, KeyTypeEd25519} {
+		key, err := GenerateKey(keyType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wif, err := EncodeWIF(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := DecodeWIF(wif)
+		if err != nil {
+			t.Fatalf("%v: %v", keyType, err)
+		}
+		if PubKeyToAddr(decoded.Public()) != PubKeyToAddr(key.Public()) {
+			t.Errorf("%v key changed after WIF round trip", keyType)
+		}
+	}
+
+	// 3. 错误的校验和、长度和版本号
+	bad := []byte(wif)
+	bad[len(bad)-1] ^= 1
+	payload := append([]byte{0x90}, one...)
+	payload = append(payload, 0x01)
+	for _, s := range []string{string(bad), wif[:len(wif)-2], string(Base58Encode(append(payload, Checksum(payload)...))), "0OIl"} {
+		if _, err := DecodeWIF(s); err != ErrInvalidWIF {
+			t.Errorf("DecodeWIF(%q) got %v", s, err)
+		}
+	}
+
+	// 4. 不能超出曲线阶
+	payload = append([]byte{WIF_VERSION_SECP256K1}, bytes.Repeat([]byte{0xff}, 32)...)
+	payload = append(payload, 0x01)
+	if _, err := DecodeWIF(string(Base58Encode(append(payload, Checksum(payload)...)))); err == nil {
+		t.Errorf("out of range key accepted")
+	}
+}
@@ -0,0 +1,132 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Alan-333333/simple-blockchain/utils"
+)
+
+// 地址簿文件
+const addressBookFile = "./dat/wallet/addressbook.json"
+
+var (
+	ErrContactNotFound = errors.New("contact not found")
+	ErrContactExists   = errors.New("contact already exists")
+)
+
+// Contact 地址簿中的联系人
+type Contact struct {
+	Name    string
+	Address string
+}
+
+// AddressBook 按名称保存的联系人地址
+type AddressBook struct {
+	Contacts map[string]string // 名称 -> 规范格式的地址
+}
+
+// LoadAddressBook 读取地址簿,文件不存在时返回空的地址簿
+func LoadAddressBook() (*AddressBook, error) {
+
+	book := &AddressBook{Contacts: make(map[string]string)}
+
+	data, err := os.ReadFile(addressBookFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return book, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, book); err != nil {
+		return nil, err
+	}
+
+	return book, nil
+}
+
+// Save 保存地址簿
+func (b *AddressBook) Save() error {
+
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeWalletFile(addressBookFile, data)
+}
+
+// Add 添加联系人,名称不能为空或包含空白字符,不能重复
+func (b *AddressBook) Add(name, address string) error {
+
+	if name == "" || strings.ContainsAny(name, " \t\n") {
+		return errors.New("invalid contact name")
+	}
+	if _, ok := b.Contacts[name]; ok {
+		return ErrContactExists
+	}
+
+	// 地址簿只保存规范格式的地址
+	address, err := utils.NormalizeAddress(address)
+	if err != nil {
+		return err
+	}
+	b.Contacts[name] = address
+
+	return nil
+}
+
+// Remove 删除联系人
+func (b *AddressBook) Remove(name string) error {
+
+	if _, ok := b.Contacts[name]; !ok {
+		return ErrContactNotFound
+	}
+	delete(b.Contacts, name)
+
+	return nil
+}
+
+// Lookup 根据名称查询联系人地址
+func (b *AddressBook) Lookup(name string) (string, error) {
+
+	address, ok := b.Contacts[name]
+	if !ok {
+		return "", ErrContactNotFound
+	}
+
+	return address, nil
+}
+
+// List 按名称排序的所有联系人
+func (b *AddressBook) List() []Contact {
+
+	contacts := make([]Contact, 0, len(b.Contacts))
+	for name, address := range b.Contacts {
+		contacts = append(contacts, Contact{Name: name, Address: address})
+	}
+	sort.Slice(contacts, func(i, j int) bool {
+		return contacts[i].Name < contacts[j].Name
+	})
+
+	return contacts
+}
+
+// ResolveAddress 将地址或联系人名称解析为规范格式的地址
+func (b *AddressBook) ResolveAddress(nameOrAddress string) (string, error) {
+
+	address, err := utils.NormalizeAddress(nameOrAddress)
+	if err == nil {
+		return address, nil
+	}
+
+	// 不是合法地址时按联系人名称查询,都不是时返回地址错误
+	if contact, ok := b.Contacts[nameOrAddress]; ok {
+		return contact, nil
+	}
+
+	return "", err
+}
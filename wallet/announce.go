@@ -0,0 +1,179 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Alan-333333/simple-blockchain/utils"
+)
+
+// 公告时间允许超前本地时间的范围
+const MAX_ANNOUNCE_CLOCK_SKEW = 2 * time.Hour
+
+// 编码后的地址公告的最大字节数
+const MAX_ANNOUNCEMENT_SIZE = 1024
+
+// 内存中保存的地址公告数量上限,超出时丢弃最早保存的地址
+const MAX_KNOWN_ANNOUNCEMENTS = 10000
+
+// 签名时使用的域分隔前缀,避免与交易签名混用
+const announceSigPrefix = "simple-blockchain address announcement:"
+
+var (
+	ErrInvalidAnnouncement = errors.New("invalid address announcement")
+	ErrStaleAnnouncement   = errors.New("stale address announcement")
+)
+
+// Announcement 地址公告,只包含公钥,由地址对应的私钥签名
+type Announcement struct {
+	Address   string
+	PublicKey []byte // 带类型的公钥编码
+	Timestamp int64
+	Signature []byte
+}
+
+// 已知的地址公告,只保存在内存中,用于去重和避免广播循环
+var (
+	announcements     = make(map[string]*Announcement)
+	announcementOrder []string // 按首次保存的顺序,用于丢弃最早的地址
+	announcementLock  sync.Mutex
+)
+
+// NewAnnouncement 为已解锁的钱包创建签名的地址公告
+func NewAnnouncement(wallet *Wallet) (*Announcement, error) {
+	if err := wallet.CanSign(); err != nil {
+		return nil, err
+	}
+
+	pubKey, err := utils.EncodePubKey(wallet.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Announcement{
+		Address:   wallet.Address,
+		PublicKey: pubKey,
+		Timestamp: time.Now().Unix(),
+	}
+	a.Signature, err = utils.SignHash(wallet.PrivateKey, a.sigHash())
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// 签名hash覆盖除签名外的所有字段
+func (a *Announcement) sigHash() []byte {
+	buf := new(bytes.Buffer)
+	buf.WriteString(announceSigPrefix)
+	binary.Write(buf, binary.LittleEndian, uint32(len(a.Address)))
+	buf.WriteString(a.Address)
+	binary.Write(buf, binary.LittleEndian, uint32(len(a.PublicKey)))
+	buf.Write(a.PublicKey)
+	binary.Write(buf, binary.LittleEndian, a.Timestamp)
+
+	hash := sha256.Sum256(buf.Bytes())
+	return hash[:]
+}
+
+// Verify 校验公钥与地址匹配且签名有效
+func (a *Announcement) Verify() error {
+
+	// 1. 解析公钥
+	pubKey, err := utils.DecodePubKey(a.PublicKey)
+	if err != nil {
+		return ErrInvalidAnnouncement
+	}
+
+	// 2. 公钥必须对应公告的地址
+	if utils.PubKeyToAddr(pubKey) != a.Address {
+		return ErrInvalidAnnouncement
+	}
+
+	// 3. 时间不能超前太多
+	if time.Unix(a.Timestamp, 0).After(time.Now().Add(MAX_ANNOUNCE_CLOCK_SKEW)) {
+		return ErrInvalidAnnouncement
+	}
+
+	// 4. 校验签名
+	if !utils.VerifySignature(pubKey, a.sigHash(), a.Signature) {
+		return ErrInvalidAnnouncement
+	}
+
+	return nil
+}
+
+// EncodeAnnouncement 编码地址公告
+func EncodeAnnouncement(a *Announcement) ([]byte, error) {
+	return json.Marshal(a)
+}
+
+// DecodeAnnouncement 解码并校验地址公告
+// 包含未知字段(如私钥)的消息直接拒绝
+func DecodeAnnouncement(data []byte) (*Announcement, error) {
+
+	if len(data) > MAX_ANNOUNCEMENT_SIZE {
+		return nil, ErrInvalidAnnouncement
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var a Announcement
+	if err := dec.Decode(&a); err != nil {
+		return nil, ErrInvalidAnnouncement
+	}
+	if dec.More() {
+		return nil, ErrInvalidAnnouncement
+	}
+
+	if err := a.Verify(); err != nil {
+		return nil, err
+	}
+
+	return &a, nil
+}
+
+// Save 保存地址公告,已有相同或更新的公告时返回ErrStaleAnnouncement
+// 数量达到上限时丢弃最早保存的地址的公告
+func (a *Announcement) Save() error {
+	announcementLock.Lock()
+	defer announcementLock.Unlock()
+
+	known, ok := announcements[a.Address]
+	if ok && known.Timestamp >= a.Timestamp {
+		return ErrStaleAnnouncement
+	}
+
+	if !ok {
+		if len(announcementOrder) >= MAX_KNOWN_ANNOUNCEMENTS {
+			delete(announcements, announcementOrder[0])
+			announcementOrder = announcementOrder[1:]
+		}
+		announcementOrder = append(announcementOrder, a.Address)
+	}
+	saved := *a
+	announcements[a.Address] = &saved
+
+	return nil
+}
+
+// GetAnnouncement 查询已知地址的公告
+func GetAnnouncement(address string) *Announcement {
+	announcementLock.Lock()
+	defer announcementLock.Unlock()
+
+	known, ok := announcements[address]
+	if !ok {
+		return nil
+	}
+	a := *known
+
+	return &a
+}
@@ -0,0 +1,106 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestAnnouncement(t *testing.T) {
+
+	chdirTemp(t)
+
+	wallet := NewWallet()
+	announcement, err := NewAnnouncement(wallet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := EncodeAnnouncement(announcement)
+
+	// 1. 编码后只包含公钥
+	if bytes.Contains(data, []byte("PrivateKey")) {
+		t.Errorf("announcement contains private key")
+	}
+
+	decoded, err := DecodeAnnouncement(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 2. 篡改地址或使用其他钱包的公钥
+	forged := *decoded
+	forged.Address = NewWallet().Address
+	if err := forged.Verify(); err != ErrInvalidAnnouncement {
+		t.Errorf("forged address got %v", err)
+	}
+	forged = *decoded
+	forged.Timestamp++
+	if err := forged.Verify(); err != ErrInvalidAnnouncement {
+		t.Errorf("tampered timestamp got %v", err)
+	}
+
+	// 3. 携带私钥等未知字段的消息被拒绝
+	var fields map[string]interface{}
+	json.Unmarshal(data, &fields)
+	encoded, _ := EncodedWallet(wallet)
+	fields["PrivateKey"] = encoded
+	withKey, _ := json.Marshal(fields)
+	if _, err := DecodeAnnouncement(withKey); err != ErrInvalidAnnouncement {
+		t.Errorf("announcement with private key got %v", err)
+	}
+
+	// 4. 相同公告只保存一次
+	if err := decoded.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := decoded.Save(); err != ErrStaleAnnouncement {
+		t.Errorf("saving known announcement got %v", err)
+	}
+	if known := GetAnnouncement(wallet.Address); known == nil || !bytes.Equal(known.PublicKey, decoded.PublicKey) {
+		t.Errorf("announcement not saved")
+	}
+
+	// 5. 超过大小限制的消息被拒绝
+	delete(fields, "PrivateKey")
+	fields["Signature"] = bytes.Repeat([]byte{1}, MAX_ANNOUNCEMENT_SIZE)
+	oversized, _ := json.Marshal(fields)
+	if _, err := DecodeAnnouncement(oversized); err != ErrInvalidAnnouncement {
+		t.Errorf("oversized announcement got %v", err)
+	}
+
+	// 锁定的钱包不能签名公告
+	if _, err := NewAnnouncement(&Wallet{PublicKey: wallet.PublicKey, Address: wallet.Address}); err != ErrWalletLocked {
+		t.Errorf("locked wallet got %v", err)
+	}
+}
+
+func TestAnnouncementLimit(t *testing.T) {
+
+	announcementLock.Lock()
+	saved, savedOrder := announcements, announcementOrder
+	announcements, announcementOrder = make(map[string]*Announcement), nil
+	announcementLock.Unlock()
+	defer func() {
+		announcementLock.Lock()
+		announcements, announcementOrder = saved, savedOrder
+		announcementLock.Unlock()
+	}()
+
+	// 超过上限时丢弃最早保存的地址
+	for i := 0; i <= MAX_KNOWN_ANNOUNCEMENTS; i++ {
+		a := &Announcement{Address: fmt.Sprintf("addr%d", i), Timestamp: 1}
+		if err := a.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(announcements) != MAX_KNOWN_ANNOUNCEMENTS {
+		t.Errorf("got %d announcements, want %d", len(announcements), MAX_KNOWN_ANNOUNCEMENTS)
+	}
+	if GetAnnouncement("addr0") != nil {
+		t.Errorf("oldest announcement not evicted")
+	}
+	if GetAnnouncement(fmt.Sprintf("addr%d", MAX_KNOWN_ANNOUNCEMENTS)) == nil {
+		t.Errorf("newest announcement missing")
+	}
+}
@@ -0,0 +1,105 @@
+package wallet
+
+import (
+	"errors"
+
+	"github.com/Alan-333333/simple-blockchain/utils"
+)
+
+// 分割的秘密类型,恢复时区分单个私钥和HD钱包种子
+const (
+	backupKindKey  = byte(1)
+	backupKindSeed = byte(2)
+)
+
+var ErrBackupKind = errors.New("shares are not a backup of this kind of wallet")
+
+// SplitKey 将已解锁钱包的私钥分割为n份,任意m份可以恢复钱包
+func (wallet *Wallet) SplitKey(n, m int) ([]string, error) {
+
+	if err := wallet.CanSign(); err != nil {
+		return nil, err
+	}
+	key, err := utils.MarshalPrivateKey(wallet.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return splitBackup(backupKindKey, key, n, m)
+}
+
+// SplitSeed 将HD钱包的种子分割为n份,任意m份可以恢复所有派生地址
+func (hd *HDWallet) SplitSeed(passphrase string, n, m int) ([]string, error) {
+
+	seed, err := hd.seed(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return splitBackup(backupKindSeed, seed, n, m)
+}
+
+// RecoverWallet 使用份额恢复私钥,并使用口令加密保存为钱包
+func RecoverWallet(shares []string, passphrase string) (*Wallet, error) {
+
+	data, err := combineBackup(backupKindKey, shares)
+	if err != nil {
+		return nil, err
+	}
+	key, err := utils.UnmarshalPrivateKey(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return importKey(key, passphrase)
+}
+
+// RecoverHDWallet 使用份额恢复HD钱包种子,并扫描已使用的地址
+func RecoverHDWallet(shares []string, passphrase string, used AddressUsage) (*HDWallet, error) {
+
+	seed, err := combineBackup(backupKindSeed, shares)
+	if err != nil {
+		return nil, err
+	}
+
+	return restoreHDWallet(seed, passphrase, used)
+}
+
+// 分割带类型的秘密,返回编码后的份额
+func splitBackup(kind byte, secret []byte, n, m int) ([]string, error) {
+
+	shares, err := utils.SplitSecret(append([]byte{kind}, secret...), n, m)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded := make([]string, len(shares))
+	for i, share := range shares {
+		encoded[i] = share.Encode()
+	}
+
+	return encoded, nil
+}
+
+// 解析份额并恢复指定类型的秘密
+func combineBackup(kind byte, encoded []string) ([]byte, error) {
+
+	shares := make([]*utils.Share, len(encoded))
+	for i, s := range encoded {
+		share, err := utils.DecodeShare(s)
+		if err != nil {
+			return nil, err
+		}
+		shares[i] = share
+	}
+
+	secret, err := utils.CombineShares(shares)
+	if err != nil {
+		return nil, err
+	}
+	if len(secret) == 0 || secret[0] != kind {
+		return nil, ErrBackupKind
+	}
+
+	return secret[1:], nil
+}
@@ -0,0 +1,74 @@
+package wallet
+
+import (
+	"os"
+	"testing"
+
+	"github.com/Alan-333333/simple-blockchain/utils"
+)
+
+func TestShamirBackup(t *testing.T) {
+
+	chdirTemp(t)
+	scryptN = 1 << 10
+	defer func() { scryptN = SCRYPT_N }()
+
+	// 1. 私钥分割为5份,任意3份恢复
+	owner, err := NewWalletOfType(utils.KeyTypeEd25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shares, err := owner.SplitKey(5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RecoverWallet(shares[:2], "passphrase"); err != utils.ErrNotEnoughShares {
+		t.Errorf("2 shares got %v", err)
+	}
+	recovered, err := RecoverWallet([]string{shares[4], shares[0], shares[2]}, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recovered.Address != owner.Address {
+		t.Errorf("recovered %s, want %s", recovered.Address, owner.Address)
+	}
+	if _, err := Unlock(owner.Address, "passphrase", 0); err != nil {
+		t.Error(err)
+	}
+	Lock(owner.Address)
+
+	// 2. HD钱包种子分割后恢复所有已使用的地址
+	hd, _, err := CreateHDWallet("passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := hd.NewReceiveAddress("passphrase", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seedShares, err := hd.SplitSeed("passphrase", 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := hd.SplitSeed("wrong", 3, 2); err != ErrWrongPassphrase {
+		t.Errorf("split with wrong passphrase got %v", err)
+	}
+
+	// 私钥份额不能恢复HD钱包,反之亦然
+	if _, err := RecoverHDWallet(shares[:3], "passphrase", nil); err != ErrBackupKind {
+		t.Errorf("key shares as hd backup got %v", err)
+	}
+	if _, err := RecoverWallet(seedShares[:2], "passphrase"); err != ErrBackupKind {
+		t.Errorf("seed shares as key backup got %v", err)
+	}
+
+	os.RemoveAll("./dat")
+	used := func(address string) bool { return address == first.Address }
+	restored, err := RecoverHDWallet(seedShares[1:], "new passphrase", used)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !restored.IsMine(first.Address) || len(restored.Receive) != 1 {
+		t.Errorf("restored hd wallet addresses %v", restored.Receive)
+	}
+}
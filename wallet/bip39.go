@@ -0,0 +1,112 @@
+package wallet
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// 默认助记词熵长度,128位对应12个单词
+const MNEMONIC_ENTROPY_BITS = 128
+
+var ErrInvalidMnemonic = errors.New("invalid mnemonic")
+
+// NewMnemonic 生成指定熵长度的BIP39助记词
+func NewMnemonic(bits int) (string, error) {
+
+	if bits < 128 || bits > 256 || bits%32 != 0 {
+		return "", errors.New("mnemonic entropy must be 128-256 bits in steps of 32")
+	}
+
+	entropy := make([]byte, bits/8)
+	if _, err := rand.Read(entropy); err != nil {
+		return "", err
+	}
+
+	return EntropyToMnemonic(entropy)
+}
+
+// EntropyToMnemonic 将熵编码为助记词
+// 熵后追加SHA256的前len/32位作为校验,每11位对应一个单词
+func EntropyToMnemonic(entropy []byte) (string, error) {
+
+	bits := len(entropy) * 8
+	if bits < 128 || bits > 256 || bits%32 != 0 {
+		return "", ErrInvalidMnemonic
+	}
+
+	// 1. 追加校验和
+	checksum := sha256.Sum256(entropy)
+	data := append(append([]byte{}, entropy...), checksum[0])
+	total := bits + bits/32
+
+	// 2. 每11位取一个单词
+	words := make([]string, total/11)
+	for i := range words {
+		idx := 0
+		for j := 0; j < 11; j++ {
+			idx = idx<<1 | bitAt(data, i*11+j)
+		}
+		words[i] = bip39WordList[idx]
+	}
+
+	return strings.Join(words, " "), nil
+}
+
+// MnemonicToEntropy 解码助记词并校验校验和
+func MnemonicToEntropy(mnemonic string) ([]byte, error) {
+
+	words := strings.Fields(mnemonic)
+	if len(words) < 12 || len(words) > 24 || len(words)%3 != 0 {
+		return nil, ErrInvalidMnemonic
+	}
+
+	// 1. 单词转换为位串
+	total := len(words) * 11
+	data := make([]byte, (total+7)/8)
+	for i, word := range words {
+		idx, ok := bip39WordIndex[word]
+		if !ok {
+			return nil, ErrInvalidMnemonic
+		}
+		for j := 0; j < 11; j++ {
+			if idx&(1<<(10-j)) != 0 {
+				pos := i*11 + j
+				data[pos/8] |= 0x80 >> (pos % 8)
+			}
+		}
+	}
+
+	// 2. 校验和
+	bits := total * 32 / 33
+	entropy := data[:bits/8]
+	checksum := sha256.Sum256(entropy)
+	for i := 0; i < bits/32; i++ {
+		if bitAt(data, bits+i) != bitAt(checksum[:], i) {
+			return nil, ErrInvalidMnemonic
+		}
+	}
+
+	return entropy, nil
+}
+
+// MnemonicToSeed 根据助记词和可选口令生成64字节种子
+// 词表和口令只支持ASCII,不做NFKD规范化
+func MnemonicToSeed(mnemonic, passphrase string) ([]byte, error) {
+
+	if _, err := MnemonicToEntropy(mnemonic); err != nil {
+		return nil, err
+	}
+
+	normalized := strings.Join(strings.Fields(mnemonic), " ")
+	return pbkdf2.Key([]byte(normalized), []byte("mnemonic"+passphrase), 2048, 64, sha512.New), nil
+}
+
+// 取第i位(高位在前)
+func bitAt(data []byte, i int) int {
+	return int(data[i/8]>>(7-i%8)) & 1
+}
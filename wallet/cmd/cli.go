@@ -23,7 +23,8 @@ func simulate() {
 	walletB := wallet.NewWallet()
 
 	// 2. 构造交易
-	tx := transaction.NewTransaction(walletA.GetAddress(), walletB.GetAddress(), 10)
+	amount := 10 * transaction.COIN
+	tx := transaction.NewTransaction(walletA.GetAddress(), walletB.GetAddress(), amount, 0, 0)
 
 	// 3. 签名
 	tx.Sign(walletA.PrivateKey)
@@ -43,12 +44,14 @@ func simulate() {
 	go bc.Mine(txPool)
 
 	// 5. 模拟执行
-	fmt.Println("Transfer 10 coins from", walletA.GetAddress(), "to", walletB.GetAddress())
+	fmt.Println("Transfer", amount, "coins from", walletA.GetAddress(), "to", walletB.GetAddress())
 
 	// 6. 更新余额
-	walletA.Balance -= 10
-	walletB.Balance += 10
+	walletA.Balance -= amount
+	walletB.Balance += amount
 
+	walletA.Encrypt("passphrase-a")
+	walletB.Encrypt("passphrase-b")
 	walletA.Save()
 	walletB.Save()
 
@@ -0,0 +1,171 @@
+package wallet
+
+import (
+	"crypto"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/Alan-333333/simple-blockchain/utils"
+)
+
+// 加密私钥PEM的块类型
+const PEM_KEY_TYPE = "ENCRYPTED WALLET KEY"
+
+var ErrInvalidPEM = errors.New("invalid encrypted PEM key")
+
+// ExportWIF 导出WIF格式的私钥,钱包需要已解锁
+func (wallet *Wallet) ExportWIF() (string, error) {
+	if err := wallet.CanSign(); err != nil {
+		return "", err
+	}
+	return utils.EncodeWIF(wallet.PrivateKey)
+}
+
+// ExportPEM 导出使用口令加密的PEM格式私钥,钱包需要已解锁
+// 加密方式与钱包文件相同,scrypt参数、盐和随机数保存在PEM头中,地址作为附加数据
+func (wallet *Wallet) ExportPEM(passphrase string) ([]byte, error) {
+
+	if err := wallet.CanSign(); err != nil {
+		return nil, err
+	}
+
+	// 1. 加密私钥
+	plain, err := utils.MarshalPrivateKey(wallet.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	kc, err := encryptSecret(plain, []byte(wallet.Address), passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. 编码为PEM
+	block := &pem.Block{
+		Type: PEM_KEY_TYPE,
+		Headers: map[string]string{
+			"Address": wallet.Address,
+			"KDF":     kc.KDF,
+			"N":       strconv.Itoa(kc.KDFParams.N),
+			"R":       strconv.Itoa(kc.KDFParams.R),
+			"P":       strconv.Itoa(kc.KDFParams.P),
+			"Salt":    hex.EncodeToString(kc.KDFParams.Salt),
+			"Cipher":  kc.Cipher,
+			"Nonce":   hex.EncodeToString(kc.Nonce),
+		},
+		Bytes: kc.CipherText,
+	}
+
+	return pem.EncodeToMemory(block), nil
+}
+
+// ExportPublicKey 导出十六进制公钥,格式为"类型:hex",可直接用于ParsePublicKeyHex
+func (wallet *Wallet) ExportPublicKey() (string, error) {
+
+	if wallet.PublicKey == nil {
+		return "", errors.New("wallet has no public key")
+	}
+	t, err := utils.KeyTypeOf(wallet.PublicKey)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s:%x", t, utils.MarshalPubKey(wallet.PublicKey)), nil
+}
+
+// DecodePEM 使用口令解密PEM格式的私钥
+func DecodePEM(data []byte, passphrase string) (crypto.Signer, error) {
+
+	// 1. 解析PEM头
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != PEM_KEY_TYPE {
+		return nil, ErrInvalidPEM
+	}
+	h := block.Headers
+	kc := &keyCrypto{
+		KDF:        h["KDF"],
+		Cipher:     h["Cipher"],
+		CipherText: block.Bytes,
+	}
+	var err error
+	if kc.KDFParams.N, err = strconv.Atoi(h["N"]); err != nil {
+		return nil, ErrInvalidPEM
+	}
+	if kc.KDFParams.R, err = strconv.Atoi(h["R"]); err != nil {
+		return nil, ErrInvalidPEM
+	}
+	if kc.KDFParams.P, err = strconv.Atoi(h["P"]); err != nil {
+		return nil, ErrInvalidPEM
+	}
+	if kc.KDFParams.Salt, err = hex.DecodeString(h["Salt"]); err != nil {
+		return nil, ErrInvalidPEM
+	}
+	if kc.Nonce, err = hex.DecodeString(h["Nonce"]); err != nil {
+		return nil, ErrInvalidPEM
+	}
+	kc.KDFParams.KeyLen = scryptKeyLen
+
+	// 2. 解密,地址被篡改时解密失败
+	plain, err := decryptSecret(kc, []byte(h["Address"]), passphrase)
+	if err != nil {
+		return nil, err
+	}
+	key, err := utils.UnmarshalPrivateKey(plain)
+	if err != nil {
+		return nil, err
+	}
+	if utils.PubKeyToAddr(key.Public()) != h["Address"] {
+		return nil, errors.New("PEM key does not match address")
+	}
+
+	return key, nil
+}
+
+// ImportWIF 导入WIF格式的私钥,使用口令加密保存为钱包
+func ImportWIF(wif, passphrase string) (*Wallet, error) {
+
+	key, err := utils.DecodeWIF(wif)
+	if err != nil {
+		return nil, err
+	}
+
+	return importKey(key, passphrase)
+}
+
+// ImportPEM 导入加密PEM格式的私钥,pemPassphrase用于解密PEM,passphrase用于加密钱包
+func ImportPEM(data []byte, pemPassphrase, passphrase string) (*Wallet, error) {
+
+	key, err := DecodePEM(data, pemPassphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return importKey(key, passphrase)
+}
+
+// 保存导入的私钥,已有私钥的钱包不覆盖,只读钱包升级为可签名的钱包并保留余额
+func importKey(key crypto.Signer, passphrase string) (*Wallet, error) {
+
+	wallet := newWalletFromKey(key)
+
+	existing, err := loadWallet(wallet.Address)
+	switch {
+	case err == nil && !existing.watchOnly:
+		return nil, ErrWalletExists
+	case err == nil:
+		wallet.Balance = existing.Balance
+	case err != ErrWalletNotFound:
+		return nil, err
+	}
+
+	if err := wallet.Encrypt(passphrase); err != nil {
+		return nil, err
+	}
+	if err := wallet.Save(); err != nil {
+		return nil, err
+	}
+
+	return wallet, nil
+}
@@ -0,0 +1,115 @@
+package wallet
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/Alan-333333/simple-blockchain/transaction"
+	"github.com/Alan-333333/simple-blockchain/utils"
+)
+
+func TestImportExport(t *testing.T) {
+
+	chdirTemp(t)
+	scryptN = 1 << 10
+	defer func() { scryptN = SCRYPT_N }()
+
+	for _, keyType := range []utils.KeyType{utils.KeyTypeP256, utils.KeyTypeSecp256k1, utils.KeyTypeEd25519} {
+
+		owner, err := NewWalletOfType(keyType)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// 1. 导出WIF并导入,地址不变且钱包加密保存
+		wif, err := owner.ExportWIF()
+		if err != nil {
+			t.Fatal(err)
+		}
+		imported, err := ImportWIF(wif, "import pass")
+		if err != nil {
+			t.Fatalf("%v: %v", keyType, err)
+		}
+		if imported.Address != owner.Address || !imported.IsEncrypted() {
+			t.Errorf("%v imported wallet %s, want %s", keyType, imported.Address, owner.Address)
+		}
+		if _, err := Unlock(owner.Address, "import pass", 0); err != nil {
+			t.Errorf("%v unlock imported wallet: %v", keyType, err)
+		}
+		Lock(owner.Address)
+
+		// 2. 不能覆盖已有私钥的钱包
+		if _, err := ImportWIF(wif, "other"); err != ErrWalletExists {
+			t.Errorf("%v importing existing wallet got %v", keyType, err)
+		}
+
+		// 3. 加密PEM往返
+		data, err := owner.ExportPEM("pem pass")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(data), wif) {
+			t.Errorf("PEM contains plaintext key")
+		}
+		if _, err := DecodePEM(data, "wrong"); err != ErrWrongPassphrase {
+			t.Errorf("%v wrong PEM passphrase got %v", keyType, err)
+		}
+		key, err := DecodePEM(data, "pem pass")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if utils.PubKeyToAddr(key.Public()) != owner.Address {
+			t.Errorf("%v PEM key changed", keyType)
+		}
+
+		// 4. 导出的公钥可以创建只读钱包
+		pubHex, err := owner.ExportPublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pub, err := ParsePublicKeyHex(pubHex)
+		if err != nil {
+			t.Fatalf("%v: %v", keyType, err)
+		}
+		if utils.PubKeyToAddr(pub) != owner.Address {
+			t.Errorf("%v exported public key %s does not match address", keyType, pubHex)
+		}
+	}
+
+	// 5. 导入只读钱包的私钥时升级为可签名钱包并保留余额
+	owner := NewWallet()
+	watch, _ := NewWatchOnlyWallet(owner.Address, nil)
+	watch.UpdateWalletBalance(3 * transaction.COIN)
+	if err := watch.Save(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := owner.ExportPEM("pem pass")
+	imported, err := ImportPEM(data, "pem pass", "wallet pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := GetwalletByAddress(owner.Address)
+	if imported.Balance != 3*transaction.COIN || loaded.IsWatchOnly() || loaded.Balance != 3*transaction.COIN {
+		t.Errorf("watch-only wallet not upgraded")
+	}
+
+	// 6. 锁定的钱包不能导出私钥
+	if _, err := loaded.ExportWIF(); err != ErrWalletLocked {
+		t.Errorf("exporting locked wallet got %v", err)
+	}
+
+	// 7. 篡改PEM中的地址
+	tampered := strings.Replace(string(data), owner.Address, NewWallet().Address, 1)
+	if _, err := DecodePEM([]byte(tampered), "pem pass"); err == nil {
+		t.Errorf("tampered PEM address accepted")
+	}
+
+	// 8. 拒绝超过加密时使用的scrypt参数
+	for _, header := range []string{"N: 1073741824", "R: 1048576", "P: 1048576"} {
+		expensive := regexp.MustCompile(`(?m)^`+header[:1]+`: \d+$`).ReplaceAllString(string(data), header)
+		if _, err := DecodePEM([]byte(expensive), "pem pass"); err != ErrScryptParams {
+			t.Errorf("PEM with %s got %v", header, err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Alan-333333/simple-blockchain/transaction"
+)
+
+// 链上使用账户模型,交易只有一个发送方和一个接收方,直接从发送方余额中扣除金额和手续费。
+// 没有可供选择的未花费输出,因此不做币选择(branch-and-bound/knapsack),
+// 剩余余额留在发送方账户中,也不生成找零输出和找零地址。
+// 链上引入输出模型之前,FundTx只负责余额检查、扣除手续费和发送全部余额。
+
+var ErrAmountTooSmall = errors.New("amount does not cover the fee")
+
+// ErrInsufficientFunds 余额不足以支付金额和手续费
+type ErrInsufficientFunds struct {
+	Available transaction.Amount
+	Required  transaction.Amount
+}
+
+func (e ErrInsufficientFunds) Error() string {
+	return fmt.Sprintf("insufficient funds: available %s, required %s", e.Available, e.Required)
+}
+
+// Spendable 钱包可用于发送的余额,已发送的交易在发送时已从余额中扣除
+func (wallet *Wallet) Spendable() transaction.Amount {
+	if wallet.Balance < 0 {
+		return 0
+	}
+	return wallet.Balance
+}
+
+// FundTx 检查钱包余额能否支付交易的金额和手续费,需要在签名前调用
+// subtractFee为true时手续费从发送金额中扣除,接收方收到的金额减少手续费;
+// 发送全部余额时金额设为Spendable()并从中扣除手续费
+func (wallet *Wallet) FundTx(tx *transaction.Transaction, subtractFee bool) error {
+
+	// 1. 从金额中扣除手续费
+	value := tx.Value
+	if subtractFee {
+		var err error
+		value, err = tx.Value.Sub(tx.Fee)
+		if err != nil || value == 0 {
+			return ErrAmountTooSmall
+		}
+	}
+
+	// 2. 余额必须能支付金额和手续费,失败时不修改交易
+	required, err := value.Add(tx.Fee)
+	if err != nil {
+		return err
+	}
+	if available := wallet.Spendable(); required > available {
+		return ErrInsufficientFunds{Available: available, Required: required}
+	}
+	tx.Value = value
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/Alan-333333/simple-blockchain/transaction"
+)
+
+func TestFundTx(t *testing.T) {
+
+	wallet := NewWallet()
+	wallet.UpdateWalletBalance(10 * transaction.COIN)
+
+	// 1. 余额足够
+	tx := transaction.NewTransaction(wallet.Address, "bob", 9*transaction.COIN, transaction.COIN, 0)
+	if err := wallet.FundTx(tx, false); err != nil {
+		t.Errorf("funded transaction got %v", err)
+	}
+
+	// 2. 余额不足时返回需要和可用的金额
+	tx = transaction.NewTransaction(wallet.Address, "bob", 10*transaction.COIN, 1, 0)
+	err := wallet.FundTx(tx, false)
+	if e, ok := err.(ErrInsufficientFunds); !ok || e.Available != 10*transaction.COIN || e.Required != 10*transaction.COIN+1 {
+		t.Errorf("insufficient funds got %v", err)
+	}
+
+	// 3. 发送全部余额,手续费从金额中扣除
+	tx = transaction.NewTransaction(wallet.Address, "bob", wallet.Spendable(), transaction.COIN, 0)
+	if err := wallet.FundTx(tx, true); err != nil {
+		t.Fatal(err)
+	}
+	if tx.Value != 9*transaction.COIN {
+		t.Errorf("send max value got %s", tx.Value)
+	}
+
+	// 4. 金额不足以支付手续费
+	tx = transaction.NewTransaction(wallet.Address, "bob", 1, 1, 0)
+	if err := wallet.FundTx(tx, true); err != ErrAmountTooSmall {
+		t.Errorf("amount below fee got %v", err)
+	}
+}
+
+func TestFundTxEdgeCases(t *testing.T) {
+
+	wallet := NewWallet()
+	wallet.UpdateWalletBalance(10 * transaction.COIN)
+
+	// 1. 金额加手续费正好等于余额
+	tx := transaction.NewTransaction(wallet.Address, "bob", 10*transaction.COIN-1, 1, 0)
+	if err := wallet.FundTx(tx, false); err != nil {
+		t.Errorf("exact balance got %v", err)
+	}
+
+	// 2. 只有手续费超出余额
+	tx = transaction.NewTransaction(wallet.Address, "bob", 10*transaction.COIN, transaction.COIN, 0)
+	if _, ok := wallet.FundTx(tx, false).(ErrInsufficientFunds); !ok {
+		t.Errorf("fee above balance accepted")
+	}
+
+	// 3. 扣除手续费后仍然不足时不修改交易金额
+	tx = transaction.NewTransaction(wallet.Address, "bob", 20*transaction.COIN, transaction.COIN, 0)
+	err := wallet.FundTx(tx, true)
+	if e, ok := err.(ErrInsufficientFunds); !ok || e.Required != 20*transaction.COIN {
+		t.Errorf("subtract fee above balance got %v", err)
+	}
+	if tx.Value != 20*transaction.COIN {
+		t.Errorf("failed funding changed value to %s", tx.Value)
+	}
+
+	// 4. 手续费等于或大于金额
+	for _, fee := range []transaction.Amount{transaction.COIN, 2 * transaction.COIN} {
+		tx = transaction.NewTransaction(wallet.Address, "bob", transaction.COIN, fee, 0)
+		if err := wallet.FundTx(tx, true); err != ErrAmountTooSmall {
+			t.Errorf("fee %s got %v", fee, err)
+		}
+	}
+
+	// 5. 零手续费扣除后金额不变
+	tx = transaction.NewTransaction(wallet.Address, "bob", transaction.COIN, 0, 0)
+	if err := wallet.FundTx(tx, true); err != nil || tx.Value != transaction.COIN {
+		t.Errorf("zero fee got %v, value %s", err, tx.Value)
+	}
+
+	// 6. 金额和手续费之和超出范围
+	tx = transaction.NewTransaction(wallet.Address, "bob", transaction.MAX_MONEY, 1, 0)
+	if err := wallet.FundTx(tx, false); err != transaction.ErrAmountOutOfRange {
+		t.Errorf("out of range total got %v", err)
+	}
+
+	// 7. 余额为零或为负时发送全部余额
+	for _, balance := range []transaction.Amount{0, -transaction.COIN} {
+		wallet.UpdateWalletBalance(balance)
+		if wallet.Spendable() != 0 {
+			t.Errorf("spendable of balance %s got %s", balance, wallet.Spendable())
+		}
+		tx = transaction.NewTransaction(wallet.Address, "bob", wallet.Spendable(), 1, 0)
+		if err := wallet.FundTx(tx, true); err != ErrAmountTooSmall {
+			t.Errorf("send max of balance %s got %v", balance, err)
+		}
+		tx = transaction.NewTransaction(wallet.Address, "bob", 1, 0, 0)
+		if e, ok := wallet.FundTx(tx, false).(ErrInsufficientFunds); !ok || e.Available != 0 {
+			t.Errorf("send from balance %s got %v", balance, e)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package wallet
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// 强化派生的序号起点
+const HD_HARDENED uint32 = 0x80000000
+
+// BIP44路径中本链使用的币种编号
+const HD_COIN_TYPE uint32 = 333
+
+// P-256曲线的主密钥HMAC密钥(SLIP-0010)
+const hdSeedKey = "Nist256p1 seed"
+
+var ErrInvalidPath = errors.New("invalid derivation path")
+
+// BIP32扩展私钥
+// 私钥使用P-256曲线,派生规则遵循SLIP-0010
+type hdKey struct {
+	key       *big.Int
+	chainCode []byte
+	depth     uint8
+	index     uint32
+}
+
+// 根据种子生成主密钥
+func newMasterKey(seed []byte) *hdKey {
+
+	n := elliptic.P256().Params().N
+	data := seed
+	for {
+		sum := hmacSHA512([]byte(hdSeedKey), data)
+		key := new(big.Int).SetBytes(sum[:32])
+		if key.Sign() != 0 && key.Cmp(n) < 0 {
+			return &hdKey{key: key, chainCode: sum[32:]}
+		}
+
+		// 私钥无效时以结果作为输入重新计算
+		data = sum
+	}
+}
+
+// 派生子密钥,index不小于HD_HARDENED时为强化派生
+func (k *hdKey) child(index uint32) *hdKey {
+
+	curve := elliptic.P256()
+	n := curve.Params().N
+
+	// 1. 强化派生使用私钥,普通派生使用压缩公钥
+	var data []byte
+	if index >= HD_HARDENED {
+		data = append([]byte{0}, k.key.FillBytes(make([]byte, 32))...)
+	} else {
+		x, y := curve.ScalarBaseMult(k.key.FillBytes(make([]byte, 32)))
+		data = elliptic.MarshalCompressed(curve, x, y)
+	}
+	data = appendUint32(data, index)
+
+	for {
+		// 2. 子私钥 = IL + 父私钥 (mod n)
+		sum := hmacSHA512(k.chainCode, data)
+		il := new(big.Int).SetBytes(sum[:32])
+		if il.Cmp(n) < 0 {
+			key := il.Add(il, k.key)
+			key.Mod(key, n)
+			if key.Sign() != 0 {
+				return &hdKey{key: key, chainCode: sum[32:], depth: k.depth + 1, index: index}
+			}
+		}
+
+		// 3. 结果无效时使用 0x01 || IR || index 重新计算
+		data = append([]byte{1}, sum[32:]...)
+		data = appendUint32(data, index)
+	}
+}
+
+// 按路径依次派生
+func (k *hdKey) derive(path []uint32) *hdKey {
+	for _, index := range path {
+		k = k.child(index)
+	}
+	return k
+}
+
+// 转换为ECDSA私钥
+func (k *hdKey) privateKey() *ecdsa.PrivateKey {
+
+	curve := elliptic.P256()
+	priv := &ecdsa.PrivateKey{D: new(big.Int).Set(k.key)}
+	priv.PublicKey.Curve = curve
+	priv.PublicKey.X, priv.PublicKey.Y = curve.ScalarBaseMult(k.key.FillBytes(make([]byte, 32)))
+
+	return priv
+}
+
+// ParsePath 解析派生路径,如 m/44'/333'/0'/0/1
+func ParsePath(path string) ([]uint32, error) {
+
+	parts := strings.Split(path, "/")
+	if len(parts) == 0 || parts[0] != "m" {
+		return nil, ErrInvalidPath
+	}
+
+	indexes := make([]uint32, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		hardened := strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h")
+		if hardened {
+			part = part[:len(part)-1]
+		}
+
+		index, err := strconv.ParseUint(part, 10, 32)
+		if err != nil || uint32(index) >= HD_HARDENED {
+			return nil, ErrInvalidPath
+		}
+		if hardened {
+			index += uint64(HD_HARDENED)
+		}
+		indexes = append(indexes, uint32(index))
+	}
+
+	return indexes, nil
+}
+
+// BIP44路径 m/44'/coin'/account'/chain/index
+func bip44Path(account, chain, index uint32) string {
+	return fmt.Sprintf("m/44'/%d'/%d'/%d/%d", HD_COIN_TYPE, account, chain, index)
+}
+
+// 追加大端序的32位整数
+func appendUint32(data []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], v)
+	return append(data, buf[:]...)
+}
+
+func hmacSHA512(key, data []byte) []byte {
+	mac := hmac.New(sha512.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,79 @@
+package wallet
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestBIP39(t *testing.T) {
+
+	// 1. 词表与BIP39 english.txt一致
+	sum := sha256.Sum256([]byte(strings.Join(bip39WordList, "\n") + "\n"))
+	if hex.EncodeToString(sum[:]) != "2f5eed53a4727b4bf8880d8f3f199efc90e58503646d9ff8eff3a2ed3b24dbda" {
+		t.Fatalf("wordlist does not match BIP39 english.txt")
+	}
+
+	// 2. BIP39测试向量
+	vectors := []struct {
+		entropy  string
+		mnemonic string
+	}{
+		{"00000000000000000000000000000000", "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"},
+		{"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f", "legal winner thank year wave sausage worth useful legal winner thank yellow"},
+		{"80808080808080808080808080808080", "letter advice cage absurd amount doctor acoustic avoid letter advice cage above"},
+		{"ffffffffffffffffffffffffffffffff", "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong"},
+	}
+	for _, v := range vectors {
+		entropy, _ := hex.DecodeString(v.entropy)
+		mnemonic, err := EntropyToMnemonic(entropy)
+		if err != nil || mnemonic != v.mnemonic {
+			t.Errorf("entropy %s got %q", v.entropy, mnemonic)
+		}
+		decoded, err := MnemonicToEntropy(v.mnemonic)
+		if err != nil || hex.EncodeToString(decoded) != v.entropy {
+			t.Errorf("mnemonic %q decoded to %x, %v", v.mnemonic, decoded, err)
+		}
+	}
+
+	seed, _ := MnemonicToSeed(vectors[0].mnemonic, "TREZOR")
+	if hex.EncodeToString(seed) != "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04" {
+		t.Errorf("seed got %x", seed)
+	}
+
+	// 3. 校验和错误
+	if _, err := MnemonicToEntropy("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon"); err != ErrInvalidMnemonic {
+		t.Errorf("bad checksum got %v", err)
+	}
+}
+
+func TestHDKeyDerivation(t *testing.T) {
+
+	// SLIP-0010 nist256p1 测试向量1
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master := newMasterKey(seed)
+
+	vectors := []struct {
+		path      string
+		chainCode string
+		key       string
+	}{
+		{"m", "beeb672fe4621673f722f38529c07392fecaa61015c80c34f29ce8b41b3cb6ea", "612091aaa12e22dd2abef664f8a01a82cae99ad7441b7ef8110424915c268bc2"},
+		{"m/0'", "3460cea53e6a6bb5fb391eeef3237ffd8724bf0a40e94943c98b83825342ee11", "6939694369114c67917a182c59ddb8cafc3004e63ca5d3b84403ba8613debc0c"},
+	}
+	for _, v := range vectors {
+		path, err := ParsePath(v.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key := master.derive(path)
+		if hex.EncodeToString(key.chainCode) != v.chainCode || hex.EncodeToString(key.key.FillBytes(make([]byte, 32))) != v.key {
+			t.Errorf("%s got chain code %x key %x", v.path, key.chainCode, key.key)
+		}
+	}
+
+	if _, err := ParsePath("44'/0"); err != ErrInvalidPath {
+		t.Errorf("path without m got %v", err)
+	}
+}
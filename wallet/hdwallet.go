@@ -0,0 +1,264 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// HD钱包文件
+const hdWalletFile = "./dat/wallet/hd.wallet"
+
+// HD钱包文件格式版本
+const HD_WALLET_VERSION = 1
+
+// 连续未使用地址的上限,恢复钱包时扫描到该数量后停止
+const HD_GAP_LIMIT = 20
+
+// BIP44中的收款链和找零链
+const (
+	HD_RECEIVE_CHAIN uint32 = 0
+	HD_CHANGE_CHAIN  uint32 = 1
+)
+
+// 加密种子时使用的附加数据
+const hdSeedAAD = "hd-seed"
+
+var (
+	ErrHDWalletExists   = errors.New("hd wallet already exists")
+	ErrHDWalletNotFound = errors.New("hd wallet not found")
+	ErrGapLimit         = errors.New("too many unused addresses, gap limit reached")
+)
+
+// AddressUsage 判断地址是否已在链上使用
+type AddressUsage func(address string) bool
+
+// HDWallet 分层确定性钱包,所有地址都由助记词派生
+// 种子使用口令加密保存,派生的地址同时保存为普通的加密钱包文件
+type HDWallet struct {
+	Account uint32
+	Receive []string // 已派生的收款地址
+	Change  []string // 已派生的找零地址
+
+	crypto *keyCrypto
+}
+
+// HD钱包文件内容
+type hdWalletData struct {
+	Version int
+	Account uint32
+	Receive []string
+	Change  []string
+	Crypto  *keyCrypto
+}
+
+// CreateHDWallet 生成新的助记词并创建HD钱包,返回的助记词是唯一需要备份的数据
+func CreateHDWallet(passphrase string) (*HDWallet, string, error) {
+
+	mnemonic, err := NewMnemonic(MNEMONIC_ENTROPY_BITS)
+	if err != nil {
+		return nil, "", err
+	}
+	seed, err := MnemonicToSeed(mnemonic, "")
+	if err != nil {
+		return nil, "", err
+	}
+
+	hd, err := newHDWallet(seed, passphrase)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return hd, mnemonic, hd.Save()
+}
+
+// RestoreHDWallet 根据助记词恢复HD钱包
+// 在收款链和找零链上依次派生地址,连续HD_GAP_LIMIT个地址未使用时停止
+func RestoreHDWallet(mnemonic, passphrase string, used AddressUsage) (*HDWallet, error) {
+
+	seed, err := MnemonicToSeed(mnemonic, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return restoreHDWallet(seed, passphrase, used)
+}
+
+// 根据种子恢复HD钱包并扫描已使用的地址
+func restoreHDWallet(seed []byte, passphrase string, used AddressUsage) (*HDWallet, error) {
+
+	hd, err := newHDWallet(seed, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, chain := range []uint32{HD_RECEIVE_CHAIN, HD_CHANGE_CHAIN} {
+
+		// 1. 找到最后一个已使用的地址
+		lastUsed := -1
+		for index := 0; index-lastUsed <= HD_GAP_LIMIT; index++ {
+			w := hd.deriveWallet(seed, chain, uint32(index))
+			if used(w.Address) {
+				lastUsed = index
+			}
+		}
+
+		// 2. 保存到最后一个已使用的地址为止
+		for index := 0; index <= lastUsed; index++ {
+			if _, err := hd.addAddress(seed, chain, passphrase); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return hd, hd.Save()
+}
+
+// LoadHDWallet 读取HD钱包
+func LoadHDWallet() (*HDWallet, error) {
+
+	raw, err := os.ReadFile(hdWalletFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrHDWalletNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var data hdWalletData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, err
+	}
+	if data.Version != HD_WALLET_VERSION {
+		return nil, fmt.Errorf("unsupported hd wallet version %d", data.Version)
+	}
+	if data.Crypto == nil {
+		return nil, errors.New("hd wallet seed missing")
+	}
+
+	return &HDWallet{
+		Account: data.Account,
+		Receive: data.Receive,
+		Change:  data.Change,
+		crypto:  data.Crypto,
+	}, nil
+}
+
+// Save 保存HD钱包,种子只以加密形式保存
+func (hd *HDWallet) Save() error {
+
+	data, err := json.MarshalIndent(hdWalletData{
+		Version: HD_WALLET_VERSION,
+		Account: hd.Account,
+		Receive: hd.Receive,
+		Change:  hd.Change,
+		Crypto:  hd.crypto,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeWalletFile(hdWalletFile, data)
+}
+
+// NewReceiveAddress 派生下一个收款地址
+// 最近HD_GAP_LIMIT个收款地址都未使用时返回ErrGapLimit,避免恢复时漏掉地址
+func (hd *HDWallet) NewReceiveAddress(passphrase string, used AddressUsage) (*Wallet, error) {
+
+	if len(hd.Receive) >= HD_GAP_LIMIT {
+		unused := 0
+		for _, address := range hd.Receive[len(hd.Receive)-HD_GAP_LIMIT:] {
+			if !used(address) {
+				unused++
+			}
+		}
+		if unused == HD_GAP_LIMIT {
+			return nil, ErrGapLimit
+		}
+	}
+
+	return hd.newAddress(HD_RECEIVE_CHAIN, passphrase)
+}
+
+// NewChangeAddress 派生下一个找零地址
+func (hd *HDWallet) NewChangeAddress(passphrase string) (*Wallet, error) {
+	return hd.newAddress(HD_CHANGE_CHAIN, passphrase)
+}
+
+// IsMine 地址是否由该HD钱包派生
+func (hd *HDWallet) IsMine(address string) bool {
+	for _, addrs := range [][]string{hd.Receive, hd.Change} {
+		for _, a := range addrs {
+			if a == address {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 创建HD钱包并加密保存种子,已存在HD钱包时返回错误
+func newHDWallet(seed []byte, passphrase string) (*HDWallet, error) {
+
+	if _, err := os.Stat(hdWalletFile); err == nil {
+		return nil, ErrHDWalletExists
+	}
+
+	crypto, err := encryptSecret(seed, []byte(hdSeedAAD), passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return &HDWallet{crypto: crypto}, nil
+}
+
+// 派生下一个地址并保存
+func (hd *HDWallet) newAddress(chain uint32, passphrase string) (*Wallet, error) {
+
+	seed, err := hd.seed(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := hd.addAddress(seed, chain, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return w, hd.Save()
+}
+
+// 派生链上的下一个地址,保存为加密钱包文件并记录
+func (hd *HDWallet) addAddress(seed []byte, chain uint32, passphrase string) (*Wallet, error) {
+
+	addrs := &hd.Receive
+	if chain == HD_CHANGE_CHAIN {
+		addrs = &hd.Change
+	}
+
+	w := hd.deriveWallet(seed, chain, uint32(len(*addrs)))
+	if err := w.Encrypt(passphrase); err != nil {
+		return nil, err
+	}
+	if err := w.Save(); err != nil {
+		return nil, err
+	}
+	*addrs = append(*addrs, w.Address)
+
+	return w, nil
+}
+
+// 按BIP44路径派生钱包
+func (hd *HDWallet) deriveWallet(seed []byte, chain, index uint32) *Wallet {
+
+	path, _ := ParsePath(bip44Path(hd.Account, chain, index))
+	key := newMasterKey(seed).derive(path)
+
+	return newWalletFromKey(key.privateKey())
+}
+
+// 解密种子
+func (hd *HDWallet) seed(passphrase string) ([]byte, error) {
+	return decryptSecret(hd.crypto, []byte(hdSeedAAD), passphrase)
+}
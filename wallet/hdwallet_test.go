@@ -0,0 +1,87 @@
+package wallet
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHDWallet(t *testing.T) {
+
+	chdirTemp(t)
+	scryptN = 1 << 10
+	defer func() { scryptN = SCRYPT_N }()
+
+	// 1. 创建钱包并派生地址
+	hd, mnemonic, err := CreateHDWallet("passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(strings.Fields(mnemonic)) != 12 {
+		t.Errorf("expected 12 word mnemonic, got %q", mnemonic)
+	}
+	if _, _, err := CreateHDWallet("passphrase"); err != ErrHDWalletExists {
+		t.Errorf("second hd wallet got %v", err)
+	}
+
+	used := map[string]bool{}
+	isUsed := func(address string) bool { return used[address] }
+
+	var receive []*Wallet
+	for i := 0; i < 3; i++ {
+		w, err := hd.NewReceiveAddress("passphrase", isUsed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		receive = append(receive, w)
+	}
+	change, err := hd.NewChangeAddress("passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := hd.NewChangeAddress("wrong"); err != ErrWrongPassphrase {
+		t.Errorf("wrong passphrase got %v", err)
+	}
+
+	// 派生的地址可以用口令解锁
+	if _, err := Unlock(receive[0].Address, "passphrase", 0); err != nil {
+		t.Error(err)
+	}
+	Lock(receive[0].Address)
+
+	loaded, err := LoadHDWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.IsMine(receive[2].Address) || !loaded.IsMine(change.Address) || loaded.IsMine(NewWallet().Address) {
+		t.Errorf("loaded hd wallet addresses mismatch")
+	}
+
+	// 2. 只用助记词恢复到最后一个已使用的地址
+	used[receive[1].Address] = true
+	used[change.Address] = true
+
+	chdirTemp(t)
+	restored, err := RestoreHDWallet(mnemonic, "new passphrase", isUsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restored.Receive) != 2 || restored.Receive[1] != receive[1].Address {
+		t.Errorf("restored receive addresses %v", restored.Receive)
+	}
+	if len(restored.Change) != 1 || restored.Change[0] != change.Address {
+		t.Errorf("restored change addresses %v", restored.Change)
+	}
+	if w := GetwalletByAddress(receive[0].Address); w == nil || !w.IsEncrypted() {
+		t.Errorf("restored address not saved as encrypted wallet")
+	}
+
+	// 3. 连续未使用的地址达到上限
+	for i := len(restored.Receive); i < HD_GAP_LIMIT+2; i++ {
+		if _, err := restored.NewReceiveAddress("new passphrase", isUsed); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := restored.NewReceiveAddress("new passphrase", isUsed); err != ErrGapLimit {
+		t.Errorf("gap limit got %v", err)
+	}
+}
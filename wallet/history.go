@@ -0,0 +1,169 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/Alan-333333/simple-blockchain/transaction"
+)
+
+// 交易确认状态
+const (
+	TX_STATUS_PENDING   = "pending"
+	TX_STATUS_CONFIRMED = "confirmed"
+)
+
+// 未确认交易的高度
+const pendingHeight = -1
+
+// ChainView 重新扫描钱包历史时需要的链上数据
+type ChainView interface {
+	// GetNonce 返回地址下一笔交易应使用的序号
+	GetNonce(address string) uint64
+
+	// GetConfirmedTxs 按区块顺序返回地址在链上已确认的交易
+	GetConfirmedTxs(address string) []*HistoryEntry
+}
+
+// HistoryEntry 钱包的一条交易记录
+type HistoryEntry struct {
+	TxID      string
+	Sender    string
+	Recipient string
+	Value     transaction.Amount
+	Fee       transaction.Amount
+	Nonce     uint64
+	Height    int // 所在区块高度,未确认时为-1
+	BlockHash []byte
+}
+
+// 钱包交易历史保存路径
+func historyPath(address string) string {
+	return fmt.Sprintf("./dat/wallet/history/%s.json", address)
+}
+
+// NewHistoryEntry 根据区块中的交易创建已确认的记录
+func NewHistoryEntry(tx *transaction.Transaction, height int, blockHash []byte) *HistoryEntry {
+	return &HistoryEntry{
+		TxID:      tx.IDHex(),
+		Sender:    tx.Sender,
+		Recipient: tx.Recipient,
+		Value:     tx.Value,
+		Fee:       tx.Fee,
+		Nonce:     tx.Nonce,
+		Height:    height,
+		BlockHash: blockHash,
+	}
+}
+
+// Status 交易的确认状态
+func (e *HistoryEntry) Status() string {
+	if e.Height == pendingHeight {
+		return TX_STATUS_PENDING
+	}
+	return TX_STATUS_CONFIRMED
+}
+
+// Confirmations 交易的确认数,tipHeight为当前区块高度,未确认时为0
+func (e *HistoryEntry) Confirmations(tipHeight int) int {
+	if e.Height == pendingHeight || tipHeight < e.Height {
+		return 0
+	}
+	return tipHeight - e.Height + 1
+}
+
+// Amount 交易对地址余额的影响,发出的交易为负数并包含手续费
+func (e *HistoryEntry) Amount(address string) transaction.Amount {
+	var amount transaction.Amount
+	if e.Recipient == address {
+		amount += e.Value
+	}
+	if e.Sender == address {
+		amount -= e.Value + e.Fee
+	}
+	return amount
+}
+
+// GetHistory 查询钱包地址的交易历史,按确认顺序排列,未确认的交易在最后
+func GetHistory(address string) []*HistoryEntry {
+
+	data, err := os.ReadFile(historyPath(address))
+	if err != nil {
+		return nil
+	}
+
+	var history []*HistoryEntry
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil
+	}
+
+	return history
+}
+
+// 保存交易历史
+func saveHistory(address string, history []*HistoryEntry) error {
+
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeWalletFile(historyPath(address), data)
+}
+
+// RecordTx 将已发送或已收到的未确认交易记入地址的历史
+// 发送方相同序号的未确认交易(如提高手续费前的原交易)被替换
+func RecordTx(address string, tx *transaction.Transaction) error {
+
+	if tx.Sender != address && tx.Recipient != address {
+		return errors.New("transaction does not involve address")
+	}
+
+	entry := NewHistoryEntry(tx, pendingHeight, nil)
+
+	history := GetHistory(address)
+	kept := history[:0]
+	for _, e := range history {
+		if e.TxID == entry.TxID {
+			return nil
+		}
+		if e.Height == pendingHeight && e.Sender == tx.Sender && e.Nonce == tx.Nonce {
+			continue
+		}
+		kept = append(kept, e)
+	}
+
+	return saveHistory(address, append(kept, entry))
+}
+
+// Rescan 扫描链上交易重建地址的交易历史
+// 仍可能被确认的未确认交易保留在最后,序号已被链上其他交易使用的交易被丢弃
+func Rescan(address string, chain ChainView) ([]*HistoryEntry, error) {
+
+	// 1. 链上已确认的交易
+	var history []*HistoryEntry
+	confirmed := make(map[string]bool)
+	for _, e := range chain.GetConfirmedTxs(address) {
+		confirmed[e.TxID] = true
+		history = append(history, e)
+	}
+
+	// 2. 保留仍未确认的交易
+	for _, e := range GetHistory(address) {
+		if e.Height != pendingHeight || confirmed[e.TxID] {
+			continue
+		}
+		if e.Nonce < chain.GetNonce(e.Sender) {
+			continue
+		}
+		history = append(history, e)
+	}
+
+	if err := saveHistory(address, history); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
@@ -0,0 +1,76 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/Alan-333333/simple-blockchain/transaction"
+)
+
+// 测试用的链上数据
+type fakeChain struct {
+	nonces    map[string]uint64
+	confirmed []*HistoryEntry
+}
+
+func (c *fakeChain) GetNonce(address string) uint64 {
+	return c.nonces[address]
+}
+
+func (c *fakeChain) GetConfirmedTxs(address string) []*HistoryEntry {
+	return c.confirmed
+}
+
+func TestHistory(t *testing.T) {
+
+	chdirTemp(t)
+
+	alice, bob := NewWallet(), NewWallet()
+
+	// 1. 记录未确认的交易
+	tx1 := transaction.NewTransaction(alice.Address, bob.Address, 5, 1, 0)
+	tx1.Sign(alice.PrivateKey)
+	tx2 := transaction.NewTransaction(alice.Address, bob.Address, 3, 1, 1)
+	tx2.Sign(alice.PrivateKey)
+	RecordTx(alice.Address, tx1)
+	RecordTx(alice.Address, tx2)
+
+	history := GetHistory(alice.Address)
+	if len(history) != 2 || history[0].Status() != TX_STATUS_PENDING || history[0].Confirmations(10) != 0 {
+		t.Fatalf("pending history got %v", history)
+	}
+	if history[0].Amount(alice.Address) != -6 || history[0].Amount(bob.Address) != 5 {
+		t.Errorf("history amount got %s", history[0].Amount(alice.Address))
+	}
+
+	// 2. 提高手续费的替换交易取代原交易
+	bumped := transaction.NewTransaction(alice.Address, bob.Address, 3, 2, 1)
+	bumped.Sign(alice.PrivateKey)
+	RecordTx(alice.Address, bumped)
+	history = GetHistory(alice.Address)
+	if len(history) != 2 || history[1].TxID != bumped.IDHex() {
+		t.Errorf("replacement not recorded")
+	}
+
+	// 3. 重新扫描: tx1已确认,替换交易仍未确认
+	chain := &fakeChain{
+		nonces:    map[string]uint64{alice.Address: 1},
+		confirmed: []*HistoryEntry{NewHistoryEntry(tx1, 4, []byte{1})},
+	}
+	history, err := Rescan(alice.Address, chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].Status() != TX_STATUS_CONFIRMED || history[0].Confirmations(6) != 3 {
+		t.Errorf("confirmed history got %v", history)
+	}
+	if history[1].TxID != bumped.IDHex() || history[1].Status() != TX_STATUS_PENDING {
+		t.Errorf("pending transaction lost on rescan")
+	}
+
+	// 4. 序号已被链上其他交易使用的未确认交易被丢弃
+	chain.nonces[alice.Address] = 2
+	history, _ = Rescan(alice.Address, chain)
+	if len(history) != 1 || len(GetHistory(alice.Address)) != 1 {
+		t.Errorf("conflicted pending transaction kept")
+	}
+}
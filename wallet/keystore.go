@@ -0,0 +1,522 @@
+package wallet
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Alan-333333/simple-blockchain/transaction"
+	"github.com/Alan-333333/simple-blockchain/utils"
+	"golang.org/x/crypto/scrypt"
+)
+
+// 钱包文件格式版本,公钥或私钥明文的编码变化时递增
+//
+//	version 1: PEM格式的P-256公钥,私钥明文为DER编码的EC私钥
+//	version 2: 带类型的公钥编码,私钥明文为带类型的私钥编码
+const KEYSTORE_VERSION = 2
+
+// scrypt参数,约需32MB内存
+const (
+	SCRYPT_N = 1 << 15
+	SCRYPT_R = 8
+	SCRYPT_P = 1
+
+	scryptKeyLen  = 32
+	scryptSaltLen = 32
+)
+
+// 加密时使用的scrypt N参数,测试中调低以加快速度
+var scryptN = SCRYPT_N
+
+var (
+	ErrWalletNotFound     = errors.New("wallet not found")
+	ErrWalletExists       = errors.New("wallet already exists")
+	ErrWalletLocked       = errors.New("wallet is locked")
+	ErrWalletNotEncrypted = errors.New("wallet is not encrypted")
+	ErrWrongPassphrase    = errors.New("wrong passphrase")
+	ErrScryptParams       = errors.New("scrypt parameters out of range")
+	ErrStaleAddress       = errors.New("wallet address uses the old format, unlock the wallet to migrate it before signing")
+)
+
+// scrypt密钥派生参数
+type scryptParams struct {
+	N      int
+	R      int
+	P      int
+	KeyLen int
+	Salt   []byte
+}
+
+// 加密后的私钥
+type keyCrypto struct {
+	KDF        string
+	KDFParams  scryptParams
+	Cipher     string
+	Nonce      []byte
+	CipherText []byte
+}
+
+// 加密钱包文件,不包含明文私钥
+type keystoreFile struct {
+	Version   int
+	Address   string
+	PublicKey []byte // 带类型的公钥编码,旧文件为PEM格式
+	Balance   transaction.Amount
+	Crypto    *keyCrypto
+	WatchOnly bool `json:",omitempty"`
+
+	// 迁移后旧地址的文件只保存新地址,按旧地址查询时读取新地址的钱包
+	AliasOf string `json:",omitempty"`
+}
+
+// 已解锁的私钥
+type unlockedKey struct {
+	key   crypto.Signer
+	timer *time.Timer
+}
+
+var (
+	unlocked     = make(map[string]*unlockedKey)
+	unlockedLock sync.Mutex
+)
+
+// 钱包文件路径
+func walletPath(address string) string {
+	return fmt.Sprintf("./dat/wallet/%s.wallet", address)
+}
+
+// Encrypt 使用口令加密钱包私钥,之后Save只保存密文
+func (wallet *Wallet) Encrypt(passphrase string) error {
+	if wallet.watchOnly {
+		return ErrWatchOnly
+	}
+	if wallet.PrivateKey == nil {
+		return ErrWalletLocked
+	}
+
+	crypto, err := encryptKey(wallet.PrivateKey, wallet.Address, passphrase)
+	if err != nil {
+		return err
+	}
+	wallet.crypto = crypto
+	wallet.cryptoVersion = KEYSTORE_VERSION
+
+	return nil
+}
+
+// IsEncrypted 钱包是否以加密格式保存
+func (wallet *Wallet) IsEncrypted() bool {
+	return wallet.crypto != nil
+}
+
+// IsLocked 私钥不可用时钱包处于锁定状态
+func (wallet *Wallet) IsLocked() bool {
+	return wallet.PrivateKey == nil
+}
+
+// Unlock 使用口令解锁钱包,timeout后自动锁定,timeout为0时直到调用Lock
+// 明文钱包文件在首次解锁时使用该口令加密保存
+func Unlock(address, passphrase string, timeout time.Duration) (*Wallet, error) {
+
+	// 1. 读取钱包文件
+	wallet, err := loadWallet(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if wallet.watchOnly {
+		return nil, ErrWatchOnly
+	}
+
+	// 2. 明文钱包迁移为加密格式,旧地址的钱包在第4步迁移
+	if wallet.crypto == nil && !wallet.staleAddress {
+		if err := wallet.Encrypt(passphrase); err != nil {
+			return nil, err
+		}
+		if err := wallet.Save(); err != nil {
+			return nil, err
+		}
+	}
+
+	// 3. 解密私钥,旧的明文钱包已有私钥
+	if wallet.crypto != nil {
+		key, err := decryptKey(wallet.crypto, wallet.cryptoVersion, wallet.Address, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		wallet.PrivateKey = key
+	}
+	key := wallet.PrivateKey
+
+	// 4. 旧地址迁移到新地址,旧版本的钱包文件重新加密为当前版本
+	switch {
+	case wallet.staleAddress:
+		if err := migrateAddress(wallet, passphrase); err != nil {
+			return nil, err
+		}
+	case wallet.cryptoVersion != KEYSTORE_VERSION:
+		if err := wallet.Encrypt(passphrase); err != nil {
+			return nil, err
+		}
+		if err := wallet.Save(); err != nil {
+			return nil, err
+		}
+	}
+	address = wallet.Address
+
+	// 5. 记录已解锁的私钥,到期后自动锁定
+	unlockedLock.Lock()
+	defer unlockedLock.Unlock()
+
+	if old, ok := unlocked[address]; ok && old.timer != nil {
+		old.timer.Stop()
+	}
+	entry := &unlockedKey{key: key}
+	if timeout > 0 {
+		entry.timer = time.AfterFunc(timeout, func() {
+			unlockedLock.Lock()
+			defer unlockedLock.Unlock()
+			if unlocked[address] == entry {
+				delete(unlocked, address)
+			}
+		})
+	}
+	unlocked[address] = entry
+
+	return wallet, nil
+}
+
+// Lock 立即锁定钱包
+func Lock(address string) {
+
+	// 迁移后的旧地址锁定新地址的钱包
+	if wallet, err := loadWallet(address); err == nil {
+		address = wallet.Address
+	}
+
+	unlockedLock.Lock()
+	defer unlockedLock.Unlock()
+
+	if entry, ok := unlocked[address]; ok {
+		if entry.timer != nil {
+			entry.timer.Stop()
+		}
+		delete(unlocked, address)
+	}
+}
+
+// ChangePassphrase 修改钱包口令
+func ChangePassphrase(address, oldPassphrase, newPassphrase string) error {
+
+	// 1. 使用旧口令解密
+	wallet, err := loadWallet(address)
+	if err != nil {
+		return err
+	}
+	if wallet.watchOnly {
+		return ErrWatchOnly
+	}
+	if wallet.staleAddress {
+		return ErrStaleAddress
+	}
+	if wallet.crypto == nil {
+		return ErrWalletNotEncrypted
+	}
+	key, err := decryptKey(wallet.crypto, wallet.cryptoVersion, wallet.Address, oldPassphrase)
+	if err != nil {
+		return err
+	}
+
+	// 2. 使用新口令重新加密,盐和随机数重新生成
+	wallet.PrivateKey = key
+	if err := wallet.Encrypt(newPassphrase); err != nil {
+		return err
+	}
+
+	return wallet.Save()
+}
+
+// 获取已解锁的私钥
+func unlockedKeyFor(address string) crypto.Signer {
+	unlockedLock.Lock()
+	defer unlockedLock.Unlock()
+
+	if entry, ok := unlocked[address]; ok {
+		return entry.key
+	}
+	return nil
+}
+
+// 将旧地址的钱包迁移到由公钥计算的地址
+// 私钥以新地址作为附加数据重新加密保存,旧地址的文件改为指向新地址的别名
+func migrateAddress(wallet *Wallet, passphrase string) error {
+
+	// 1. 使用新地址加密保存,新地址已有可签名的钱包时不覆盖,地址相同则私钥相同
+	oldAddress := wallet.Address
+	wallet.Address = utils.PubKeyToAddr(wallet.PublicKey)
+	wallet.staleAddress = false
+	wallet.legacy = false
+	if err := wallet.Encrypt(passphrase); err != nil {
+		return err
+	}
+	if existing, err := loadWallet(wallet.Address); err != nil || existing.watchOnly {
+		if err := wallet.Save(); err != nil {
+			return err
+		}
+	}
+
+	// 2. 旧地址保留为别名
+	data, err := json.MarshalIndent(keystoreFile{
+		Version: KEYSTORE_VERSION,
+		Address: oldAddress,
+		AliasOf: wallet.Address,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeWalletFile(walletPath(oldAddress), data)
+}
+
+// 读取钱包文件,按旧地址查询已迁移的钱包时读取新地址的钱包
+func loadWallet(address string) (*Wallet, error) {
+
+	wallet, aliasOf, err := readWalletFile(address)
+	if err != nil || aliasOf == "" {
+		return wallet, err
+	}
+
+	wallet, aliasOf, err = readWalletFile(aliasOf)
+	if err == nil && aliasOf != "" {
+		return nil, errors.New("wallet alias points to another alias")
+	}
+
+	return wallet, err
+}
+
+// 读取钱包文件,支持加密格式和旧的明文格式,别名文件返回别名指向的地址
+func readWalletFile(address string) (*Wallet, string, error) {
+
+	data, err := os.ReadFile(walletPath(address))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, "", ErrWalletNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 1. 别名、加密格式或只读钱包
+	var file keystoreFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, "", err
+	}
+	var wallet *Wallet
+	switch {
+	case file.AliasOf != "":
+		return nil, file.AliasOf, nil
+	case file.WatchOnly:
+		wallet, err = decodeWatchOnly(&file)
+	case file.Crypto != nil:
+		wallet, err = decodeKeystore(&file)
+	default:
+		// 2. 旧的明文格式
+		wallet, err = DecodeWallet(data)
+	}
+
+	return wallet, "", err
+}
+
+// 编码为加密钱包文件
+func encodeKeystore(wallet *Wallet) ([]byte, error) {
+
+	pubKey, err := utils.EncodePubKey(wallet.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// 未重新加密的旧版本密文保留原版本号和公钥编码
+	if wallet.cryptoVersion == 1 {
+		pubASN1, err := x509.MarshalPKIXPublicKey(wallet.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		pubKey = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubASN1})
+	}
+
+	file := keystoreFile{
+		Version:   wallet.cryptoVersion,
+		Address:   wallet.Address,
+		PublicKey: pubKey,
+		Balance:   wallet.Balance,
+		Crypto:    wallet.crypto,
+	}
+
+	return json.MarshalIndent(file, "", "  ")
+}
+
+// 解码加密钱包文件,已解锁时填充私钥
+func decodeKeystore(file *keystoreFile) (*Wallet, error) {
+
+	pubKey, err := decodeWalletPubKey(file.PublicKey, file.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Wallet{
+		PrivateKey:    unlockedKeyFor(file.Address),
+		PublicKey:     pubKey,
+		Address:       file.Address,
+		Balance:       file.Balance,
+		crypto:        file.Crypto,
+		cryptoVersion: file.Version,
+
+		staleAddress: utils.PubKeyToAddr(pubKey) != file.Address,
+	}, nil
+}
+
+// 按钱包文件版本解析公钥,version 1为PEM格式,之后为带类型的编码
+func decodeWalletPubKey(data []byte, version int) (crypto.PublicKey, error) {
+
+	switch version {
+	case 1:
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("invalid keystore public key")
+		}
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case KEYSTORE_VERSION:
+		return utils.DecodePubKey(data)
+	default:
+		return nil, fmt.Errorf("unsupported keystore version %d", version)
+	}
+}
+
+// 使用口令派生的密钥和AES-GCM加密私钥,地址作为附加数据
+func encryptKey(key crypto.Signer, address, passphrase string) (*keyCrypto, error) {
+
+	plain, err := utils.MarshalPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return encryptSecret(plain, []byte(address), passphrase)
+}
+
+// 解密私钥,口令错误或密文被篡改时返回ErrWrongPassphrase
+func decryptKey(kc *keyCrypto, version int, address, passphrase string) (crypto.Signer, error) {
+
+	plain, err := decryptSecret(kc, []byte(address), passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	// version 1的钱包保存DER编码的P-256私钥
+	switch version {
+	case 1:
+		return x509.ParseECPrivateKey(plain)
+	case KEYSTORE_VERSION:
+		return utils.UnmarshalPrivateKey(plain)
+	default:
+		return nil, fmt.Errorf("unsupported keystore version %d", version)
+	}
+}
+
+// 使用口令派生的密钥和AES-GCM加密数据,附加数据用于绑定密文的用途
+func encryptSecret(plain, aad []byte, passphrase string) (*keyCrypto, error) {
+
+	// 1. 派生加密密钥
+	params := scryptParams{N: scryptN, R: SCRYPT_R, P: SCRYPT_P, KeyLen: scryptKeyLen, Salt: make([]byte, scryptSaltLen)}
+	if _, err := rand.Read(params.Salt); err != nil {
+		return nil, err
+	}
+	aead, err := newKeyCipher(passphrase, &params)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. 加密
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return &keyCrypto{
+		KDF:        "scrypt",
+		KDFParams:  params,
+		Cipher:     "aes-256-gcm",
+		Nonce:      nonce,
+		CipherText: aead.Seal(nil, nonce, plain, aad),
+	}, nil
+}
+
+// 解密数据,口令错误或密文被篡改时返回ErrWrongPassphrase
+func decryptSecret(crypto *keyCrypto, aad []byte, passphrase string) ([]byte, error) {
+
+	if crypto.KDF != "scrypt" || crypto.Cipher != "aes-256-gcm" {
+		return nil, fmt.Errorf("unsupported keystore cipher %s/%s", crypto.KDF, crypto.Cipher)
+	}
+
+	aead, err := newKeyCipher(passphrase, &crypto.KDFParams)
+	if err != nil {
+		return nil, err
+	}
+	if len(crypto.Nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid keystore nonce")
+	}
+
+	plain, err := aead.Open(nil, crypto.Nonce, crypto.CipherText, aad)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	return plain, nil
+}
+
+// 根据口令和scrypt参数创建AES-GCM
+func newKeyCipher(passphrase string, params *scryptParams) (cipher.AEAD, error) {
+
+	if params.KeyLen != scryptKeyLen {
+		return nil, errors.New("invalid keystore key length")
+	}
+	// 参数来自钱包文件或PEM头,超过加密时使用的参数会消耗过多内存和时间
+	if params.N <= 1 || params.N > SCRYPT_N || params.R < 1 || params.R > SCRYPT_R || params.P < 1 || params.P > SCRYPT_P {
+		return nil, ErrScryptParams
+	}
+	derived, err := scrypt.Key([]byte(passphrase), params.Salt, params.N, params.R, params.P, params.KeyLen)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// 确保钱包目录存在并写入文件
+func writeWalletFile(path string, data []byte) error {
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	// 先写临时文件再重命名,避免写入中断损坏钱包
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
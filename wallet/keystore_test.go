@@ -0,0 +1,262 @@
+package wallet
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Alan-333333/simple-blockchain/transaction"
+	"github.com/Alan-333333/simple-blockchain/utils"
+)
+
+// 在临时目录中运行,避免写入源码目录
+func chdirTemp(t *testing.T) {
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(dir) })
+}
+
+func TestKeystore(t *testing.T) {
+
+	chdirTemp(t)
+
+	// 1. 未加密的新钱包不能保存
+	wallet := NewWallet()
+	if err := wallet.Save(); err != ErrWalletNotEncrypted {
+		t.Errorf("saving unencrypted wallet got %v", err)
+	}
+
+	// 2. 加密保存,文件中没有明文私钥
+	if err := wallet.Encrypt("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if err := wallet.Save(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(walletPath(wallet.Address))
+	if strings.Contains(string(data), "PRIVATE KEY") {
+		t.Errorf("wallet file contains plaintext private key")
+	}
+
+	loaded := GetwalletByAddress(wallet.Address)
+	if loaded == nil || !loaded.IsLocked() {
+		t.Fatalf("loaded wallet should be locked")
+	}
+
+	// 3. 解锁
+	if _, err := Unlock(wallet.Address, "wrong", 0); err != ErrWrongPassphrase {
+		t.Errorf("wrong passphrase got %v", err)
+	}
+	if _, err := Unlock(wallet.Address, "correct horse", 0); err != nil {
+		t.Fatal(err)
+	}
+	loaded = GetwalletByAddress(wallet.Address)
+	if loaded.IsLocked() || !loaded.PrivateKey.(*ecdsa.PrivateKey).Equal(wallet.PrivateKey) {
+		t.Errorf("unlocked wallet has wrong private key")
+	}
+	Lock(wallet.Address)
+	if !GetwalletByAddress(wallet.Address).IsLocked() {
+		t.Errorf("wallet should be locked")
+	}
+
+	// 4. 到期自动锁定
+	if _, err := Unlock(wallet.Address, "correct horse", 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for !GetwalletByAddress(wallet.Address).IsLocked() {
+		if time.Now().After(deadline) {
+			t.Fatal("wallet not relocked after timeout")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// 5. 修改口令
+	if err := ChangePassphrase(wallet.Address, "wrong", "battery staple"); err != ErrWrongPassphrase {
+		t.Errorf("change with wrong passphrase got %v", err)
+	}
+	if err := ChangePassphrase(wallet.Address, "correct horse", "battery staple"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Unlock(wallet.Address, "correct horse", 0); err != ErrWrongPassphrase {
+		t.Errorf("old passphrase got %v", err)
+	}
+	if _, err := Unlock(wallet.Address, "battery staple", 0); err != nil {
+		t.Error(err)
+	}
+	Lock(wallet.Address)
+}
+
+func TestKeystoreMigrate(t *testing.T) {
+
+	chdirTemp(t)
+
+	// 1. 旧的明文钱包文件
+	wallet := NewWallet()
+	data, _ := EncodedWallet(wallet)
+	if err := writeWalletFile(walletPath(wallet.Address), data); err != nil {
+		t.Fatal(err)
+	}
+
+	// 2. 首次解锁时迁移为加密格式
+	unlockedWallet, err := Unlock(wallet.Address, "passphrase", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Lock(wallet.Address)
+
+	if !unlockedWallet.IsEncrypted() || !unlockedWallet.PrivateKey.(*ecdsa.PrivateKey).Equal(wallet.PrivateKey) {
+		t.Errorf("migrated wallet has wrong private key")
+	}
+	data, _ = os.ReadFile(walletPath(wallet.Address))
+	if strings.Contains(string(data), "PRIVATE KEY") {
+		t.Errorf("migrated wallet file contains plaintext private key")
+	}
+}
+
+func TestKeystoreKeyTypes(t *testing.T) {
+
+	chdirTemp(t)
+
+	for _, keyType := range []utils.KeyType{utils.KeyTypeP256, utils.KeyTypeSecp256k1, utils.KeyTypeEd25519} {
+
+		// 1. 加密保存后解锁
+		wallet, err := NewWalletOfType(keyType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if wallet.KeyType() != keyType {
+			t.Errorf("%v wallet reported as %v", keyType, wallet.KeyType())
+		}
+		wallet.Encrypt("passphrase")
+		if err := wallet.Save(); err != nil {
+			t.Fatal(err)
+		}
+		unlockedWallet, err := Unlock(wallet.Address, "passphrase", 0)
+		if err != nil {
+			t.Fatalf("%v: %v", keyType, err)
+		}
+
+		// 2. 解锁后的私钥可以签名交易和地址公告
+		tx := transaction.NewTransaction(wallet.Address, "receiver", 1, 0, 0)
+		if err := unlockedWallet.SignTx(tx); err != nil || !tx.IsValid() {
+			t.Errorf("%v wallet signed invalid transaction: %v", keyType, err)
+		}
+		a, err := NewAnnouncement(unlockedWallet)
+		if err != nil || a.Verify() != nil {
+			t.Errorf("%v wallet announcement invalid: %v", keyType, err)
+		}
+		Lock(wallet.Address)
+	}
+
+	// 3. version 1的钱包文件保存PEM公钥和DER编码的私钥
+	privKey, pubKey := utils.GenerateKeyPair()
+	address := utils.PubKeyToAddr(pubKey)
+	der, _ := x509.MarshalECPrivateKey(privKey)
+	pubASN1, _ := x509.MarshalPKIXPublicKey(pubKey)
+	kc, err := encryptSecret(der, []byte(address), "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(keystoreFile{
+		Version:   1,
+		Address:   address,
+		PublicKey: pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubASN1}),
+		Crypto:    kc,
+	})
+	if err := writeWalletFile(walletPath(address), data); err != nil {
+		t.Fatal(err)
+	}
+	fileVersion := func() int {
+		var file keystoreFile
+		data, _ := os.ReadFile(walletPath(address))
+		json.Unmarshal(data, &file)
+		return file.Version
+	}
+
+	// 锁定时保存余额不改变文件版本
+	legacy := GetwalletByAddress(address)
+	legacy.UpdateWalletBalance(transaction.COIN)
+	if err := legacy.Save(); err != nil || fileVersion() != 1 {
+		t.Errorf("saving locked version 1 wallet: %v, version %d", err, fileVersion())
+	}
+
+	// 解锁后重新加密为当前版本
+	unlockedWallet, err := Unlock(address, "passphrase", 0)
+	if err != nil || !privKey.Equal(unlockedWallet.PrivateKey) {
+		t.Fatalf("version 1 wallet not unlocked: %v", err)
+	}
+	Lock(address)
+	if fileVersion() != KEYSTORE_VERSION || GetwalletByAddress(address).Balance != transaction.COIN {
+		t.Errorf("version 1 wallet not upgraded")
+	}
+	if _, err := Unlock(address, "passphrase", 0); err != nil {
+		t.Errorf("upgraded wallet: %v", err)
+	}
+	Lock(address)
+}
+
+func TestStaleAddressMigrate(t *testing.T) {
+
+	chdirTemp(t)
+	scryptN = 1 << 10
+	defer func() { scryptN = SCRYPT_N }()
+
+	// 1. 旧的明文钱包文件,地址为公钥的Base58编码
+	wallet := NewWallet()
+	newAddress := wallet.Address
+	pub := wallet.PublicKey.(*ecdsa.PublicKey)
+	payload := append([]byte{0x00}, append(pub.X.Bytes(), pub.Y.Bytes()...)...)
+	oldAddress := string(utils.Base58Encode(append(payload, utils.Checksum(payload)...)))
+	wallet.Address = oldAddress
+	data, _ := EncodedWallet(wallet)
+	if err := writeWalletFile(walletPath(oldAddress), data); err != nil {
+		t.Fatal(err)
+	}
+
+	// 2. 迁移前不能签名
+	legacy := GetwalletByAddress(oldAddress)
+	tx := transaction.NewTransaction(oldAddress, newAddress, 1, 0, 0)
+	if err := legacy.SignTx(tx); err != ErrStaleAddress {
+		t.Errorf("signing with stale address got %v", err)
+	}
+	if err := ChangePassphrase(oldAddress, "", "passphrase"); err != ErrStaleAddress {
+		t.Errorf("changing passphrase of stale address got %v", err)
+	}
+
+	// 3. 解锁时迁移到新地址,私钥以新地址加密
+	unlockedWallet, err := Unlock(oldAddress, "passphrase", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unlockedWallet.Address != newAddress || !unlockedWallet.IsEncrypted() {
+		t.Fatalf("wallet migrated to %s, want %s", unlockedWallet.Address, newAddress)
+	}
+	tx = transaction.NewTransaction(newAddress, oldAddress, 1, 0, 0)
+	if err := unlockedWallet.SignTx(tx); err != nil || !tx.IsValid() {
+		t.Errorf("migrated wallet signed invalid transaction: %v", err)
+	}
+
+	// 4. 旧地址作为别名
+	if w := GetwalletByAddress(oldAddress); w == nil || w.Address != newAddress || w.IsLocked() {
+		t.Errorf("old address does not resolve to the migrated wallet")
+	}
+	Lock(oldAddress)
+	if !GetwalletByAddress(newAddress).IsLocked() {
+		t.Errorf("locking old address did not lock migrated wallet")
+	}
+	if _, err := Unlock(newAddress, "passphrase", 0); err != nil {
+		t.Errorf("unlock migrated wallet: %v", err)
+	}
+	Lock(newAddress)
+}
@@ -0,0 +1,95 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/Alan-333333/simple-blockchain/utils"
+)
+
+// 标签文件
+const labelsFile = "./dat/wallet/labels.json"
+
+// Labels 用户为地址和交易设置的标签
+type Labels struct {
+	Addresses    map[string]string
+	Transactions map[string]string
+}
+
+// LoadLabels 读取标签,文件不存在时返回空的标签
+func LoadLabels() (*Labels, error) {
+
+	labels := &Labels{
+		Addresses:    make(map[string]string),
+		Transactions: make(map[string]string),
+	}
+
+	data, err := os.ReadFile(labelsFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return labels, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, labels); err != nil {
+		return nil, err
+	}
+
+	return labels, nil
+}
+
+// Save 保存标签
+func (l *Labels) Save() error {
+
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeWalletFile(labelsFile, data)
+}
+
+// SetAddressLabel 设置地址标签,label为空时删除标签
+func (l *Labels) SetAddressLabel(address, label string) error {
+
+	address, err := utils.NormalizeAddress(address)
+	if err != nil {
+		return err
+	}
+
+	if label == "" {
+		delete(l.Addresses, address)
+	} else {
+		l.Addresses[address] = label
+	}
+
+	return nil
+}
+
+// SetTxLabel 设置交易标签,label为空时删除标签
+func (l *Labels) SetTxLabel(txid, label string) error {
+
+	if id, err := hex.DecodeString(txid); err != nil || len(id) != 32 {
+		return errors.New("invalid transaction id")
+	}
+
+	if label == "" {
+		delete(l.Transactions, txid)
+	} else {
+		l.Transactions[txid] = label
+	}
+
+	return nil
+}
+
+// AddressLabel 查询地址标签
+func (l *Labels) AddressLabel(address string) string {
+	return l.Addresses[address]
+}
+
+// TxLabel 查询交易标签
+func (l *Labels) TxLabel(txid string) string {
+	return l.Transactions[txid]
+}
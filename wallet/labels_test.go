@@ -0,0 +1,88 @@
+package wallet
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Alan-333333/simple-blockchain/utils"
+)
+
+func TestLabels(t *testing.T) {
+
+	chdirTemp(t)
+
+	wallet := NewWallet()
+	txid := strings.Repeat("ab", 32)
+
+	labels, err := LoadLabels()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := labels.SetAddressLabel(wallet.Address, "savings"); err != nil {
+		t.Fatal(err)
+	}
+	if err := labels.SetTxLabel(txid, "rent"); err != nil {
+		t.Fatal(err)
+	}
+	if err := labels.SetTxLabel("not-a-txid", "rent"); err == nil {
+		t.Errorf("invalid txid accepted")
+	}
+	labels.Save()
+
+	loaded, _ := LoadLabels()
+	if loaded.AddressLabel(wallet.Address) != "savings" || loaded.TxLabel(txid) != "rent" {
+		t.Errorf("labels not saved")
+	}
+
+	// 空标签删除
+	loaded.SetTxLabel(txid, "")
+	if loaded.TxLabel(txid) != "" {
+		t.Errorf("empty label should remove label")
+	}
+}
+
+func TestAddressBook(t *testing.T) {
+
+	chdirTemp(t)
+
+	wallet := NewWallet()
+	bech32Addr, _ := utils.PubKeyToBech32Addr(wallet.PublicKey, utils.NetworkHRP)
+
+	book, err := LoadAddressBook()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 1. 联系人地址保存为规范格式
+	if err := book.Add("alice", bech32Addr); err != nil {
+		t.Fatal(err)
+	}
+	if err := book.Add("alice", wallet.Address); err != ErrContactExists {
+		t.Errorf("duplicate contact got %v", err)
+	}
+	if err := book.Add("bob", "invalid"); err == nil {
+		t.Errorf("invalid address accepted")
+	}
+	book.Save()
+
+	loaded, _ := LoadAddressBook()
+	if address, err := loaded.Lookup("alice"); err != nil || address != wallet.Address {
+		t.Errorf("contact lookup got %s, %v", address, err)
+	}
+
+	// 2. 解析地址或联系人名称
+	if address, _ := loaded.ResolveAddress("alice"); address != wallet.Address {
+		t.Errorf("resolve contact got %s", address)
+	}
+	if address, _ := loaded.ResolveAddress(bech32Addr); address != wallet.Address {
+		t.Errorf("resolve address got %s", address)
+	}
+	if _, err := loaded.ResolveAddress("carol"); err == nil {
+		t.Errorf("unknown contact resolved")
+	}
+
+	// 3. 删除
+	if err := loaded.Remove("alice"); err != nil || len(loaded.List()) != 0 {
+		t.Errorf("contact not removed")
+	}
+}
@@ -0,0 +1,18 @@
+package wallet
+
+import (
+	"github.com/Alan-333333/simple-blockchain/utils"
+)
+
+// SignMessage 使用钱包私钥签名消息,用于证明拥有该地址,钱包需要已解锁
+func (wallet *Wallet) SignMessage(message string) (string, error) {
+	if err := wallet.CanSign(); err != nil {
+		return "", err
+	}
+	return utils.SignMessage(wallet.PrivateKey, message)
+}
+
+// VerifyMessage 验证消息签名由钱包地址对应的私钥生成,只读钱包也可以验证
+func (wallet *Wallet) VerifyMessage(message, signature string) error {
+	return utils.VerifyMessage(wallet.Address, message, signature)
+}
@@ -0,0 +1,35 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/Alan-333333/simple-blockchain/utils"
+)
+
+func TestSignMessage(t *testing.T) {
+
+	chdirTemp(t)
+
+	owner, err := NewWalletOfType(utils.KeyTypeSecp256k1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 1. 签名后只读钱包可以验证
+	sig, err := owner.SignMessage("I own this address")
+	if err != nil {
+		t.Fatal(err)
+	}
+	watch, _ := NewWatchOnlyWallet(owner.Address, nil)
+	if err := watch.VerifyMessage("I own this address", sig); err != nil {
+		t.Errorf("verify message: %v", err)
+	}
+	if err := NewWallet().VerifyMessage("I own this address", sig); err != utils.ErrMessageAddress {
+		t.Errorf("verify with other wallet got %v", err)
+	}
+
+	// 2. 只读钱包不能签名
+	if _, err := watch.SignMessage("I own this address"); err != ErrWatchOnly {
+		t.Errorf("watch-only sign got %v", err)
+	}
+}
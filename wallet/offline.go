@@ -0,0 +1,23 @@
+package wallet
+
+import (
+	"errors"
+
+	"github.com/Alan-333333/simple-blockchain/transaction"
+)
+
+// SignPartialTx 离线签名待签名交易,签名前校验交易内容和发送方
+func (wallet *Wallet) SignPartialTx(p *transaction.PartialTx) error {
+
+	if err := p.Verify(); err != nil {
+		return err
+	}
+	if p.IsSigned() {
+		return transaction.ErrPartialTxSigned
+	}
+	if p.Tx.Sender != wallet.Address {
+		return errors.New("partial transaction is not from this wallet")
+	}
+
+	return wallet.SignTx(p.Tx)
+}
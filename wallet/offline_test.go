@@ -0,0 +1,36 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/Alan-333333/simple-blockchain/transaction"
+)
+
+func TestSignPartialTx(t *testing.T) {
+
+	owner, recipient := NewWallet(), NewWallet()
+	tx := transaction.NewTransaction(owner.Address, recipient.Address, 5, 1, 0)
+
+	// 1. 联网节点上的只读钱包不能签名
+	watch, _ := NewWatchOnlyWallet(owner.Address, nil)
+	if err := watch.SignPartialTx(transaction.NewPartialTx(tx, 10, 0, 0)); err != ErrWatchOnly {
+		t.Errorf("watch-only signing got %v", err)
+	}
+
+	// 2. 其他钱包不能签名
+	if err := recipient.SignPartialTx(transaction.NewPartialTx(tx, 10, 0, 0)); err == nil {
+		t.Errorf("signed with wrong wallet")
+	}
+
+	// 3. 离线钱包签名
+	p := transaction.NewPartialTx(tx, 10, 0, 0)
+	if err := owner.SignPartialTx(p); err != nil {
+		t.Fatal(err)
+	}
+	if !p.IsSigned() || p.Verify() != nil {
+		t.Errorf("signed partial transaction not valid")
+	}
+	if err := owner.SignPartialTx(p); err != transaction.ErrPartialTxSigned {
+		t.Errorf("signing twice got %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package wallet
+
+import (
+	"sync"
+
+	"github.com/Alan-333333/simple-blockchain/transaction"
+)
+
+// 地址使用的外部签名者,如独立进程中的签名服务
+var (
+	signers     = make(map[string]transaction.Signer)
+	signersLock sync.Mutex
+)
+
+// UseSigner 使用外部签名者签名该地址的交易,钱包可以是只读钱包
+func UseSigner(signer transaction.Signer) {
+	signersLock.Lock()
+	defer signersLock.Unlock()
+
+	signers[signer.Address()] = signer
+}
+
+// RemoveSigner 取消地址的外部签名者
+func RemoveSigner(address string) {
+	signersLock.Lock()
+	defer signersLock.Unlock()
+
+	delete(signers, address)
+}
+
+// 获取地址的外部签名者
+func signerFor(address string) transaction.Signer {
+	signersLock.Lock()
+	defer signersLock.Unlock()
+
+	return signers[address]
+}
+
+// Signer 钱包的交易签名者,设置了外部签名者时优先使用,否则使用已解锁的私钥
+func (wallet *Wallet) Signer() (transaction.Signer, error) {
+
+	if signer := signerFor(wallet.Address); signer != nil {
+		return signer, nil
+	}
+	if err := wallet.CanSign(); err != nil {
+		return nil, err
+	}
+
+	return transaction.NewLocalSigner(wallet.PrivateKey)
+}
+
+// CanSignTx 检查钱包能否签名交易,有外部签名者时不需要本地私钥
+func (wallet *Wallet) CanSignTx() error {
+	_, err := wallet.Signer()
+	return err
+}
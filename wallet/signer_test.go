@@ -0,0 +1,52 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/Alan-333333/simple-blockchain/transaction"
+)
+
+func TestExternalSigner(t *testing.T) {
+
+	chdirTemp(t)
+
+	owner := NewWallet()
+	recipient := NewWallet()
+
+	// 1. 只读钱包没有签名者
+	watch, err := NewWatchOnlyWallet(owner.Address, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := watch.CanSignTx(); err != ErrWatchOnly {
+		t.Errorf("watch-only CanSignTx got %v", err)
+	}
+
+	// 2. 使用外部签名者后只读钱包可以签名交易
+	signer, err := transaction.NewLocalSigner(owner.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	UseSigner(signer)
+	defer RemoveSigner(owner.Address)
+
+	tx := transaction.NewTransaction(owner.Address, recipient.Address, transaction.COIN, 0, 0)
+	if err := watch.SignTx(tx); err != nil {
+		t.Fatal(err)
+	}
+	if !tx.IsValid() {
+		t.Errorf("externally signed transaction not valid")
+	}
+
+	// 3. 签名者只签名自己地址的交易
+	other := transaction.NewTransaction(recipient.Address, owner.Address, transaction.COIN, 0, 0)
+	if err := signer.SignTx(other); err != transaction.ErrSignerAddress {
+		t.Errorf("signing other sender got %v", err)
+	}
+
+	// 4. 取消后恢复为只读
+	RemoveSigner(owner.Address)
+	if err := watch.SignTx(tx); err != ErrWatchOnly {
+		t.Errorf("signing after RemoveSigner got %v", err)
+	}
+}
@@ -1,42 +1,76 @@
 package wallet
 
 import (
+	"crypto"
 	"crypto/ecdsa"
-	"crypto/elliptic"
-	"crypto/rand"
 	"crypto/x509"
 	"encoding/json"
 	"encoding/pem"
-	"fmt"
-	"os"
-	"path/filepath"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
 
+	"github.com/Alan-333333/simple-blockchain/transaction"
 	"github.com/Alan-333333/simple-blockchain/utils"
 )
 
 const WALLET_PATE = "wallet.dat"
 
 type Wallet struct {
-	PrivateKey *ecdsa.PrivateKey
-	PublicKey  *ecdsa.PublicKey
-	Address    string  // 地址就是公钥的Hash
-	Balance    float64 // 新增余额字段
+	PrivateKey crypto.Signer // P-256、secp256k1或Ed25519私钥
+	PublicKey  crypto.PublicKey
+	Address    string             // 地址就是公钥的Hash
+	Balance    transaction.Amount // 新增余额字段
+
+	// 加密后的私钥,为nil时钱包未加密
+	crypto *keyCrypto
+
+	// 密文所在钱包文件的版本,决定私钥明文的编码
+	cryptoVersion int
+
+	// 从旧的明文钱包文件读取
+	legacy bool
+
+	// 地址为旧格式(公钥的Base58编码),与公钥计算的地址不同,解锁时迁移
+	staleAddress bool
+
+	// 只读钱包,没有私钥
+	watchOnly bool
 }
 
+// NewWallet 创建默认密钥类型(P-256)的钱包
 func NewWallet() *Wallet {
 
+	wallet, _ := NewWalletOfType(utils.DEFAULT_KEY_TYPE)
+
+	return wallet
+}
+
+// NewWalletOfType 创建指定密钥类型的钱包
+func NewWalletOfType(keyType utils.KeyType) (*Wallet, error) {
+
 	// 1. 生成私钥
-	privKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	privKey, err := utils.GenerateKey(keyType)
+	if err != nil {
+		return nil, err
+	}
+
+	return newWalletFromKey(privKey), nil
+}
+
+// 根据私钥创建钱包
+func newWalletFromKey(privKey crypto.Signer) *Wallet {
 
 	// 2. 从私钥计算公钥
-	pubKey := privKey.PublicKey
+	pubKey := privKey.Public()
 
-	// 3. 生成地址(公钥hash)
-	address := utils.PubKeyToAddr(&pubKey)
+	// 3. 生成地址(公钥hash),版本号由密钥类型决定
+	address := utils.PubKeyToAddr(pubKey)
 
 	return &Wallet{
 		PrivateKey: privKey,
-		PublicKey:  &pubKey,
+		PublicKey:  pubKey,
 		Address:    address,
 		Balance:    0, // 初始化余额
 	}
@@ -46,80 +80,73 @@ func (w *Wallet) GetAddress() string {
 	return w.Address
 }
 
+// KeyType 钱包的密钥类型,由地址版本号决定
+func (w *Wallet) KeyType() utils.KeyType {
+	t, _, _ := utils.DecodeAddress(w.Address)
+	return t
+}
+
+// Save 保存钱包,私钥只以加密形式保存
+// 未加密的新钱包需要先调用Encrypt,旧的明文钱包在解锁迁移前保持明文格式
 func (wallet *Wallet) Save() error {
 
 	// 1. 序列化钱包数据
-	jsonData := serialize(wallet)
-
-	// 2. 将数据写入文件
-
-	path := fmt.Sprintf("./dat/wallet/%s.wallet", wallet.Address)
-
-	// 创建包含路径的文件夹
-	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+	var data []byte
+	var err error
+	switch {
+	case wallet.watchOnly:
+		// 不能用只读钱包覆盖已有私钥的钱包
+		if existing, err := loadWallet(wallet.Address); err == nil && !existing.watchOnly {
+			return ErrWalletExists
+		}
+		data, err = encodeWatchOnly(wallet)
+	case wallet.crypto != nil:
+		data, err = encodeKeystore(wallet)
+	case wallet.legacy:
+		data, err = EncodedWallet(wallet)
+	default:
+		return ErrWalletNotEncrypted
+	}
+	if err != nil {
 		return err
 	}
 
-	err := os.WriteFile(path, []byte(jsonData), 0600)
-
-	return err
-
+	// 2. 将数据写入文件
+	return writeWalletFile(walletPath(wallet.Address), data)
 }
 
-func (wallet *Wallet) UpdateWalletBalance(amount float64) {
+func (wallet *Wallet) UpdateWalletBalance(amount transaction.Amount) {
 	wallet.Balance = amount
 }
 
-// 查询钱包
+// 查询钱包,加密钱包未解锁时PrivateKey为nil
 func GetwalletByAddress(address string) *Wallet {
 
-	// 1. 打开钱包文件
-	walletFile := fmt.Sprintf("./dat/wallet/%s.wallet", address)
-	fileData, err := os.ReadFile(walletFile)
+	wallet, err := loadWallet(address)
 	if err != nil {
 		return nil
 	}
 
-	// 2. 反序列化数据到钱包结构体
-	wallet := deserialize(string(fileData))
-
-	// 3. 返回钱包的余额
 	return wallet
 }
 
 // 查询地址余额
-func GetAddressBalance(address string) float64 {
+func GetAddressBalance(address string) transaction.Amount {
 
-	// 1. 打开钱包文件
-	walletFile := fmt.Sprintf("./dat/wallet/%s.wallet", address)
-	fileData, err := os.ReadFile(walletFile)
+	wallet, err := loadWallet(address)
 	if err != nil {
 		return 0
 	}
 
-	// 2. 反序列化数据到钱包结构体
-	wallet := deserialize(string(fileData))
-
-	// 3. 返回钱包的余额
 	return wallet.Balance
 }
 
-func serialize(wallet *Wallet) string {
-	json, _ := EncodedWallet(wallet)
-	return string(json)
-}
-
-func deserialize(data string) *Wallet {
-
-	wallet, _ := DecodeWallet([]byte(data))
-	return wallet
-}
-
+// NodeWallet 旧的明文钱包文件格式,余额以币为单位的浮点数保存
 type NodeWallet struct {
 	PublicKey  []byte
 	PrivateKey []byte
 	Address    string
-	Balance    float64
+	Balance    json.Number
 }
 
 func EncodedWallet(wallet *Wallet) ([]byte, error) {
@@ -133,21 +160,28 @@ func EncodedWallet(wallet *Wallet) ([]byte, error) {
 		Bytes: pubASN1,
 	})
 
-	priASN1, err := x509.MarshalECPrivateKey(wallet.PrivateKey)
-
-	if err != nil {
-		return []byte{}, nil
+	// 锁定的钱包不包含私钥,旧格式只支持P-256私钥
+	var priByte []byte
+	if wallet.PrivateKey != nil {
+		privKey, ok := wallet.PrivateKey.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("legacy wallet format only supports ECDSA keys")
+		}
+		priASN1, err := x509.MarshalECPrivateKey(privKey)
+		if err != nil {
+			return []byte{}, nil
+		}
+		priByte = pem.EncodeToMemory(&pem.Block{
+			Type:  "PRIVATE KEY",
+			Bytes: priASN1,
+		})
 	}
-	priByte := pem.EncodeToMemory(&pem.Block{
-		Type:  "PRIVATE KEY",
-		Bytes: priASN1,
-	})
 
 	ewallet := NodeWallet{
 		PublicKey:  pubBytes,
 		PrivateKey: priByte,
 		Address:    wallet.Address,
-		Balance:    wallet.Balance,
+		Balance:    json.Number(wallet.Balance.String()),
 	}
 
 	return json.Marshal(ewallet)
@@ -164,23 +198,68 @@ func DecodeWallet(data []byte) (*Wallet, error) {
 	}
 
 	block, _ := pem.Decode(ewallet.PublicKey)
+	if block == nil {
+		return nil, errors.New("invalid public key in wallet")
+	}
 	pubKey, err := x509.ParsePKIXPublicKey(block.Bytes)
 	if err != nil {
 		return nil, err
 	}
 
-	block, _ = pem.Decode(ewallet.PrivateKey)
-	privKey, err := x509.ParseECPrivateKey(block.Bytes)
+	var privKey crypto.Signer
+	if block, _ = pem.Decode(ewallet.PrivateKey); block != nil {
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		privKey = key
+	}
+
+	balance, err := parseLegacyBalance(ewallet.Balance)
 	if err != nil {
 		return nil, err
 	}
 
+	// 没有私钥的钱包作为只读钱包
 	wallet := &Wallet{
-		PublicKey:  pubKey.(*ecdsa.PublicKey),
+		PublicKey:  pubKey,
 		PrivateKey: privKey,
 		Address:    ewallet.Address,
-		Balance:    ewallet.Balance,
+		Balance:    balance,
+		legacy:     privKey != nil,
+		watchOnly:  privKey == nil,
+
+		staleAddress: utils.PubKeyToAddr(pubKey) != ewallet.Address,
 	}
 
 	return wallet, nil
 }
+
+// 将旧格式中以币为单位的余额转换为最小单位
+// 浮点数可能有超过8位的小数或指数形式,此时四舍五入到最小单位
+func parseLegacyBalance(n json.Number) (transaction.Amount, error) {
+
+	s := n.String()
+	if s == "" {
+		return 0, nil
+	}
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	amount, err := transaction.ParseAmount(s)
+	if err != nil {
+		f, ferr := strconv.ParseFloat(s, 64)
+		if ferr != nil {
+			return 0, err
+		}
+		amount = transaction.Amount(math.Round(f * float64(transaction.COIN)))
+		if !amount.IsValid() {
+			return 0, transaction.ErrAmountOutOfRange
+		}
+	}
+	if negative {
+		amount = -amount
+	}
+
+	return amount, nil
+}
@@ -1,7 +1,10 @@
 package wallet
 
 import (
+	"strings"
 	"testing"
+
+	"github.com/Alan-333333/simple-blockchain/transaction"
 )
 
 func TestEncodedWallet(t *testing.T) {
@@ -32,3 +35,31 @@ func TestEncodedWallet(t *testing.T) {
 	}
 
 }
+
+func TestDecodeLegacyBalance(t *testing.T) {
+
+	wallet := NewWallet()
+	data, err := EncodedWallet(wallet)
+	if err != nil {
+		t.Fatalf("encode wallet failed: %v", err)
+	}
+
+	// 旧钱包文件的余额以币为单位的浮点数保存
+	cases := map[string]transaction.Amount{
+		"12.5":                transaction.Amount(1250000000),
+		"12":                  transaction.Amount(12) * transaction.COIN,
+		"0.1":                 transaction.Amount(10000000),
+		"1e-8":                transaction.Amount(1),
+		"0.30000000000000004": transaction.Amount(30000000),
+	}
+	for balance, want := range cases {
+		legacy := strings.Replace(string(data), `"Balance":0`, `"Balance":`+balance, 1)
+		restored, err := DecodeWallet([]byte(legacy))
+		if err != nil {
+			t.Fatalf("decode balance %s failed: %v", balance, err)
+		}
+		if restored.Balance != want {
+			t.Errorf("balance %s decoded as %d, want %d", balance, restored.Balance, want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package wallet
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Alan-333333/simple-blockchain/transaction"
+	"github.com/Alan-333333/simple-blockchain/utils"
+)
+
+var ErrWatchOnly = errors.New("watch-only wallet has no private key and cannot sign")
+
+// NewWatchOnlyWallet 创建只读钱包,只保存地址或公钥,不能签名
+// pubKey可以为nil,不为nil时必须与地址匹配,address为空时由公钥计算
+func NewWatchOnlyWallet(address string, pubKey crypto.PublicKey) (*Wallet, error) {
+
+	if address == "" && pubKey != nil {
+		address = utils.PubKeyToAddr(pubKey)
+	}
+
+	// 1. 校验地址,Bech32地址转换为规范格式
+	address, err := utils.NormalizeAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. 公钥必须对应该地址
+	if pubKey != nil && utils.PubKeyToAddr(pubKey) != address {
+		return nil, errors.New("public key does not match address")
+	}
+
+	return &Wallet{
+		PublicKey: pubKey,
+		Address:   address,
+		watchOnly: true,
+	}, nil
+}
+
+// ParsePublicKeyHex 解析十六进制编码的公钥,可以用"类型:"前缀指定密钥类型,如"ed25519:<hex>"
+// 默认为P-256,ECDSA公钥支持压缩和非压缩格式
+func ParsePublicKeyHex(s string) (crypto.PublicKey, error) {
+
+	// 1. 密钥类型
+	keyType := utils.DEFAULT_KEY_TYPE
+	if i := strings.IndexByte(s, ':'); i >= 0 {
+		t, err := utils.ParseKeyType(s[:i])
+		if err != nil {
+			return nil, err
+		}
+		keyType, s = t, s[i+1:]
+	}
+
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. 非压缩的ECDSA公钥
+	if len(data) == 65 && keyType != utils.KeyTypeEd25519 {
+		curve := elliptic.P256()
+		if keyType == utils.KeyTypeSecp256k1 {
+			curve = utils.S256()
+		}
+		x, y := elliptic.Unmarshal(curve, data)
+		if x == nil {
+			return nil, errors.New("invalid public key")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	return utils.UnmarshalPubKey(keyType, data)
+}
+
+// IsWatchOnly 是否为只读钱包
+func (wallet *Wallet) IsWatchOnly() bool {
+	return wallet.watchOnly
+}
+
+// CanSign 检查钱包能否签名,只读钱包返回ErrWatchOnly,未解锁返回ErrWalletLocked
+func (wallet *Wallet) CanSign() error {
+	if wallet.watchOnly {
+		return ErrWatchOnly
+	}
+	if wallet.staleAddress {
+		return ErrStaleAddress
+	}
+	if wallet.IsLocked() {
+		return ErrWalletLocked
+	}
+	return nil
+}
+
+// SignTx 使用钱包的签名者签名交易
+func (wallet *Wallet) SignTx(tx *transaction.Transaction) error {
+	signer, err := wallet.Signer()
+	if err != nil {
+		return err
+	}
+	return signer.SignTx(tx)
+}
+
+// 编码只读钱包文件
+func encodeWatchOnly(wallet *Wallet) ([]byte, error) {
+
+	file := keystoreFile{
+		Version:   KEYSTORE_VERSION,
+		Address:   wallet.Address,
+		Balance:   wallet.Balance,
+		WatchOnly: true,
+	}
+
+	if wallet.PublicKey != nil {
+		pubKey, err := utils.EncodePubKey(wallet.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		file.PublicKey = pubKey
+	}
+
+	return json.MarshalIndent(file, "", "  ")
+}
+
+// 解码只读钱包文件
+func decodeWatchOnly(file *keystoreFile) (*Wallet, error) {
+
+	if file.Version < 1 || file.Version > KEYSTORE_VERSION {
+		return nil, fmt.Errorf("unsupported keystore version %d", file.Version)
+	}
+
+	var pubKey crypto.PublicKey
+	if len(file.PublicKey) > 0 {
+		key, err := decodeWalletPubKey(file.PublicKey, file.Version)
+		if err != nil {
+			return nil, err
+		}
+		pubKey = key
+	}
+
+	wallet, err := NewWatchOnlyWallet(file.Address, pubKey)
+	if err != nil {
+		return nil, err
+	}
+	wallet.Balance = file.Balance
+
+	return wallet, nil
+}
@@ -0,0 +1,67 @@
+package wallet
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/hex"
+	"testing"
+
+	"github.com/Alan-333333/simple-blockchain/transaction"
+)
+
+func TestWatchOnlyWallet(t *testing.T) {
+
+	chdirTemp(t)
+
+	owner := NewWallet()
+
+	// 1. 只用地址创建只读钱包
+	watch, err := NewWatchOnlyWallet(owner.Address, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	watch.UpdateWalletBalance(5 * transaction.COIN)
+	if err := watch.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := GetwalletByAddress(owner.Address)
+	if loaded == nil || !loaded.IsWatchOnly() || loaded.Balance != 5*transaction.COIN {
+		t.Fatalf("watch-only wallet not loaded")
+	}
+
+	// 2. 不能签名
+	tx := transaction.NewTransaction(owner.Address, "bob", 1, 0, 0)
+	if err := loaded.SignTx(tx); err != ErrWatchOnly {
+		t.Errorf("signing with watch-only wallet got %v", err)
+	}
+	if _, err := NewAnnouncement(loaded); err != ErrWatchOnly {
+		t.Errorf("announcing watch-only wallet got %v", err)
+	}
+	if _, err := Unlock(owner.Address, "passphrase", 0); err != ErrWatchOnly {
+		t.Errorf("unlocking watch-only wallet got %v", err)
+	}
+
+	// 3. 使用公钥创建,公钥必须与地址匹配
+	ownerKey := owner.PublicKey.(*ecdsa.PublicKey)
+	pubHex := hex.EncodeToString(elliptic.MarshalCompressed(elliptic.P256(), ownerKey.X, ownerKey.Y))
+	pubKey, err := ParsePublicKeyHex(pubHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, err := NewWatchOnlyWallet("", pubKey); err != nil || w.Address != owner.Address {
+		t.Errorf("watch-only wallet from public key got %v", err)
+	}
+	if _, err := NewWatchOnlyWallet(NewWallet().Address, pubKey); err == nil {
+		t.Errorf("mismatched public key should fail")
+	}
+
+	// 4. 不能覆盖已有私钥的钱包
+	owned := NewWallet()
+	owned.Encrypt("passphrase")
+	owned.Save()
+	watchOwned, _ := NewWatchOnlyWallet(owned.Address, nil)
+	if err := watchOwned.Save(); err != ErrWalletExists {
+		t.Errorf("overwriting wallet with watch-only got %v", err)
+	}
+}